@@ -0,0 +1,519 @@
+package backtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"moonshot/bot"
+	"moonshot/performance"
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Multiplier curves mapping the Fear & Greed Index to the investment multiplier
+const (
+	CurveFNG    = "fng"    // The live bot's curve, rescaled to the multiplier bounds
+	CurveLinear = "linear" // Straight line from the maximum at F&G 0 to the minimum at 100
+	CurveFlat   = "flat"   // Always 1, plain DCA
+)
+
+// Buffer curves deciding the share of cash held back for dips
+const (
+	BufferDynamic = "dynamic" // The live bot's buffer, from none in extreme fear to 20% in greed
+	BufferLinear  = "linear"  // Straight line from none at F&G 0 to the maximum buffer at 100
+	BufferNone    = "none"    // Every period's investment is spent in full
+)
+
+// minBuyAmount is the smallest simulated buy in USDC, like the bot's minimum order
+var minBuyAmount = decimal.NewFromInt(1)
+
+var hundred = decimal.NewFromInt(100)
+
+// liveCurveMin and liveCurveMax are the bounds of the live bot's F&G multiplier curve
+var (
+	liveCurveMin = decimal.NewFromFloat(0.5)
+	liveCurveMax = decimal.NewFromFloat(2.0)
+)
+
+// Params is one configuration to simulate
+type Params struct {
+	Frequency       string                     `json:"frequency"`
+	BaseInvestment  decimal.Decimal            `json:"base_investment"` // USDC per week, prorated for other frequencies
+	MultiplierCurve string                     `json:"multiplier_curve"`
+	MinMultiplier   decimal.Decimal            `json:"min_multiplier"`
+	MaxMultiplier   decimal.Decimal            `json:"max_multiplier"`
+	BufferCurve     string                     `json:"buffer_curve"`
+	MaxBuffer       decimal.Decimal            `json:"max_buffer_percent"` // linear buffer curve only
+	Allocations     map[string]decimal.Decimal `json:"allocations"`        // percent by asset
+}
+
+// Validate checks the parameters can be simulated
+func (p Params) Validate() error {
+	if _, err := p.schedule(); err != nil {
+		return err
+	}
+	if !p.BaseInvestment.IsPositive() {
+		return fmt.Errorf("base investment must be positive")
+	}
+	switch p.MultiplierCurve {
+	case CurveFNG, CurveLinear, CurveFlat:
+	default:
+		return fmt.Errorf("unsupported multiplier curve: %s (must be fng, linear or flat)", p.MultiplierCurve)
+	}
+	if !p.MinMultiplier.IsPositive() || p.MaxMultiplier.LessThan(p.MinMultiplier) {
+		return fmt.Errorf("multiplier bounds must be positive with the minimum at most the maximum")
+	}
+	switch p.BufferCurve {
+	case BufferDynamic, BufferNone:
+	case BufferLinear:
+		if p.MaxBuffer.IsNegative() || p.MaxBuffer.GreaterThanOrEqual(hundred) {
+			return fmt.Errorf("max buffer must be at least 0 and below 100 percent")
+		}
+	default:
+		return fmt.Errorf("unsupported buffer curve: %s (must be dynamic, linear or none)", p.BufferCurve)
+	}
+
+	total := decimal.Zero
+	for asset, allocation := range p.Allocations {
+		if allocation.IsNegative() {
+			return fmt.Errorf("allocation of %s cannot be negative", asset)
+		}
+		total = total.Add(allocation)
+	}
+	if !total.Equal(hundred) {
+		return fmt.Errorf("allocations must sum to 100, got %s", total.String())
+	}
+	return nil
+}
+
+// Name describes the parameters in one line, e.g. "weekly 100 fng 0.5-2 buffer dynamic BTC80/ETH20"
+func (p Params) Name() string {
+	name := fmt.Sprintf("%s %s %s", strings.ToLower(p.Frequency), p.BaseInvestment.String(), p.MultiplierCurve)
+	if p.MultiplierCurve != CurveFlat {
+		name += fmt.Sprintf(" %s-%s", p.MinMultiplier.String(), p.MaxMultiplier.String())
+	}
+	name += " buffer " + p.BufferCurve
+	if p.BufferCurve == BufferLinear {
+		name += fmt.Sprintf(" %s%%", p.MaxBuffer.String())
+	}
+
+	var allocations []string
+	for _, asset := range p.Assets() {
+		allocations = append(allocations, asset+p.Allocations[asset].String())
+	}
+	return name + " " + strings.Join(allocations, "/")
+}
+
+// Assets returns the assets with a positive allocation, sorted
+func (p Params) Assets() []string {
+	var assets []string
+	for asset, allocation := range p.Allocations {
+		if allocation.IsPositive() {
+			assets = append(assets, asset)
+		}
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// schedule returns the run times of the frequency, at midnight UTC like the daily candles
+func (p Params) schedule() (*bot.Schedule, error) {
+	return bot.NewSchedule(&types.BotConfig{
+		InvestmentFrequency: p.Frequency,
+		ExecutionTime:       "00:00",
+		ExecutionWeekday:    "monday",
+		ExecutionDayOfMonth: 1,
+		Timezone:            "UTC",
+	})
+}
+
+// contribution returns the USDC paid in each period, so every frequency invests the same per week
+func (p Params) contribution() decimal.Decimal {
+	switch strings.ToLower(p.Frequency) {
+	case bot.FrequencyDaily:
+		return p.BaseInvestment.Div(decimal.NewFromInt(7)).Round(2)
+	case bot.FrequencyBiweekly:
+		return p.BaseInvestment.Mul(decimal.NewFromInt(2))
+	case bot.FrequencyMonthly:
+		return p.BaseInvestment.Mul(decimal.NewFromInt(52)).Div(decimal.NewFromInt(12)).Round(2)
+	default:
+		return p.BaseInvestment
+	}
+}
+
+// multiplier returns the investment multiplier at an F&G value
+func (p Params) multiplier(fng int) decimal.Decimal {
+	spread := p.MaxMultiplier.Sub(p.MinMultiplier)
+	switch p.MultiplierCurve {
+	case CurveFNG:
+		score := services.FNGMultiplier(fng).Sub(liveCurveMin).Div(liveCurveMax.Sub(liveCurveMin))
+		return p.MinMultiplier.Add(spread.Mul(score)).Round(4)
+	case CurveLinear:
+		score := decimal.NewFromInt(int64(100 - fng)).Div(hundred)
+		return p.MinMultiplier.Add(spread.Mul(score)).Round(4)
+	default:
+		return decimal.NewFromInt(1)
+	}
+}
+
+// buffer returns the share of cash held back at an F&G value
+func (p Params) buffer(fng int) decimal.Decimal {
+	switch p.BufferCurve {
+	case BufferDynamic:
+		return bot.DynamicBuffer(fng)
+	case BufferLinear:
+		return p.MaxBuffer.Div(hundred).Mul(decimal.NewFromInt(int64(fng))).Div(hundred)
+	default:
+		return decimal.Zero
+	}
+}
+
+// Data is the daily price and sentiment history a backtest runs on
+type Data struct {
+	opens  map[string]map[int64]decimal.Decimal
+	closes map[string][]types.Candle
+	fng    map[int64]int
+	start  time.Time
+	end    time.Time
+}
+
+// NewData indexes daily candles by asset and the daily Fear & Greed history for backtests
+func NewData(candles map[string][]types.Candle, fng []types.FearGreedIndex) *Data {
+	data := &Data{
+		opens:  make(map[string]map[int64]decimal.Decimal),
+		closes: make(map[string][]types.Candle),
+		fng:    make(map[int64]int),
+	}
+
+	for asset, series := range candles {
+		sorted := append([]types.Candle{}, series...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Start.Before(sorted[j].Start)
+		})
+		data.closes[asset] = sorted
+		data.opens[asset] = make(map[int64]decimal.Decimal, len(sorted))
+		for _, candle := range sorted {
+			data.opens[asset][day(candle.Start).Unix()] = candle.Open
+		}
+		if len(sorted) > 0 {
+			data.start = later(data.start, day(sorted[0].Start))
+			data.end = earlier(data.end, day(sorted[len(sorted)-1].Start).AddDate(0, 0, 1))
+		}
+	}
+
+	for _, index := range fng {
+		data.fng[day(index.Timestamp).Unix()] = index.Value
+	}
+	if len(fng) > 0 {
+		data.start = later(data.start, day(fng[0].Timestamp))
+		data.end = earlier(data.end, day(fng[len(fng)-1].Timestamp).AddDate(0, 0, 1))
+	}
+
+	return data
+}
+
+// Range returns the days covered by the prices of every asset and the F&G history
+func (d *Data) Range() (time.Time, time.Time) {
+	return d.start, d.end
+}
+
+// FNG returns the F&G value of a day, or of the closest earlier day within a week if it is missing
+func (d *Data) FNG(t time.Time) (int, bool) {
+	for i := 0; i < 7; i++ {
+		if value, ok := d.fng[day(t).AddDate(0, 0, -i).Unix()]; ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// open returns the opening price of an asset on a day
+func (d *Data) open(asset string, t time.Time) (decimal.Decimal, bool) {
+	price, ok := d.opens[asset][day(t).Unix()]
+	return price, ok && price.IsPositive()
+}
+
+// close returns the last close of an asset before end
+func (d *Data) close(asset string, end time.Time) (decimal.Decimal, bool) {
+	series := d.closes[asset]
+	i := sort.Search(len(series), func(i int) bool {
+		return !series[i].Start.Before(end)
+	})
+	if i == 0 {
+		return decimal.Zero, false
+	}
+	return series[i-1].Close, true
+}
+
+// Point is the state of a simulated portfolio after a scheduled run
+type Point struct {
+	Time        time.Time       `json:"time"`
+	FNG         int             `json:"fng"`
+	Value       decimal.Decimal `json:"value"`       // assets and cash
+	Contributed decimal.Decimal `json:"contributed"` // USDC paid in so far
+	Invested    decimal.Decimal `json:"invested"`    // USDC spent on buys so far
+	DCAValue    decimal.Decimal `json:"dca_value"`   // plain DCA benchmark
+}
+
+// Buy is a simulated buy
+type Buy struct {
+	Time     time.Time       `json:"time"`
+	Asset    string          `json:"asset"`
+	Amount   decimal.Decimal `json:"amount"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// Benchmark is the outcome of plain DCA: each period's contribution bought in full at the same prices
+type Benchmark struct {
+	FinalValue  decimal.Decimal            `json:"final_value"`
+	IRR         decimal.Decimal            `json:"irr"`
+	MaxDrawdown decimal.Decimal            `json:"max_drawdown"`
+	AverageCost map[string]decimal.Decimal `json:"average_cost"`
+}
+
+// Result is the outcome of one backtest
+type Result struct {
+	Name        string                     `json:"name"`
+	Params      Params                     `json:"params"`
+	Start       time.Time                  `json:"start"`
+	End         time.Time                  `json:"end"`
+	Periods     int                        `json:"periods"`
+	Contributed decimal.Decimal            `json:"contributed"` // USDC paid in
+	Invested    decimal.Decimal            `json:"invested"`    // USDC spent on buys
+	Cash        decimal.Decimal            `json:"cash"`        // USDC held back at the end
+	FinalValue  decimal.Decimal            `json:"final_value"` // assets at the last close plus cash
+	IRR         decimal.Decimal            `json:"irr"`         // annualized money-weighted return, percent
+	MaxDrawdown decimal.Decimal            `json:"max_drawdown"`
+	AverageCost map[string]decimal.Decimal `json:"average_cost"`
+	CostVsDCA   decimal.Decimal            `json:"cost_vs_dca"` // percent more of each asset per USDC than plain DCA, by allocation
+	DCA         Benchmark                  `json:"dca"`
+	Curve       []Point                    `json:"-"`
+	Buys        []Buy                      `json:"-"`
+}
+
+// Run simulates the parameters over [start, end). Each scheduled run pays in the period's
+// contribution, then buys the contribution scaled by the multiplier, limited to the cash the buffer
+// leaves investable. Buys are made at the opening price of the day and fees are not modeled.
+// Periods without a price for every asset or an F&G value are skipped.
+func Run(data *Data, params Params, start, end time.Time) (*Result, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	schedule, err := params.schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	assets := params.Assets()
+	contribution := params.contribution()
+	strategy, dca := newAccount(), newAccount()
+	result := &Result{
+		Name:   params.Name(),
+		Params: params,
+		Start:  start,
+		End:    end,
+	}
+
+	for t := schedule.Next(start.Add(-time.Nanosecond)); !t.IsZero() && t.Before(end); t = schedule.Next(t) {
+		fng, ok := data.FNG(t)
+		if !ok {
+			continue
+		}
+		prices := make(map[string]decimal.Decimal)
+		for _, asset := range assets {
+			if price, ok := data.open(asset, t); ok {
+				prices[asset] = price
+			}
+		}
+		if len(prices) < len(assets) {
+			continue
+		}
+		result.Periods++
+
+		strategy.deposit(t, contribution, prices)
+		dca.deposit(t, contribution, prices)
+
+		// Scale the contribution by the multiplier, keeping the buffer's share of cash back
+		investable := strategy.cash.Mul(decimal.NewFromInt(1).Sub(params.buffer(fng)))
+		spend := decimal.Min(contribution.Mul(params.multiplier(fng)), investable)
+		for _, asset := range assets {
+			share := params.Allocations[asset].Div(hundred)
+			if buy, ok := strategy.buy(t, asset, spend.Mul(share).RoundDown(2), prices[asset]); ok {
+				result.Buys = append(result.Buys, buy)
+			}
+			dca.buy(t, asset, contribution.Mul(share).RoundDown(2), prices[asset])
+		}
+
+		strategy.mark(prices)
+		dca.mark(prices)
+		result.Curve = append(result.Curve, Point{
+			Time:        t,
+			FNG:         fng,
+			Value:       strategy.value(prices).Round(2),
+			Contributed: strategy.contributed,
+			Invested:    strategy.invested,
+			DCAValue:    dca.value(prices).Round(2),
+		})
+	}
+
+	if result.Periods == 0 {
+		return nil, fmt.Errorf("no scheduled runs with prices and F&G values between %s and %s",
+			start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	finalPrices := make(map[string]decimal.Decimal)
+	for _, asset := range assets {
+		if price, ok := data.close(asset, end); ok {
+			finalPrices[asset] = price
+		}
+	}
+
+	result.Contributed = strategy.contributed
+	result.Invested = strategy.invested
+	result.Cash = strategy.cash.Round(2)
+	result.FinalValue, result.IRR, result.MaxDrawdown, result.AverageCost = strategy.finish(end, finalPrices)
+	result.DCA.FinalValue, result.DCA.IRR, result.DCA.MaxDrawdown, result.DCA.AverageCost = dca.finish(end, finalPrices)
+	result.CostVsDCA = costVsDCA(params, result.AverageCost, result.DCA.AverageCost)
+
+	return result, nil
+}
+
+// account is a simulated portfolio of cash and assets. Its drawdown is measured on a unit value
+// that deposits don't move, like a fund's share price.
+type account struct {
+	cash        decimal.Decimal
+	contributed decimal.Decimal
+	invested    decimal.Decimal
+	quantity    map[string]decimal.Decimal
+	cost        map[string]decimal.Decimal
+	units       decimal.Decimal
+	peak        decimal.Decimal
+	maxDrawdown decimal.Decimal
+	flows       []performance.CashFlow
+}
+
+// newAccount creates an empty account
+func newAccount() *account {
+	return &account{
+		quantity: make(map[string]decimal.Decimal),
+		cost:     make(map[string]decimal.Decimal),
+	}
+}
+
+// value returns the cash plus the assets at the given prices
+func (a *account) value(prices map[string]decimal.Decimal) decimal.Decimal {
+	value := a.cash
+	for asset, quantity := range a.quantity {
+		value = value.Add(quantity.Mul(prices[asset]))
+	}
+	return value
+}
+
+// deposit pays in cash, issuing units at the current unit value
+func (a *account) deposit(t time.Time, amount decimal.Decimal, prices map[string]decimal.Decimal) {
+	unitValue := decimal.NewFromInt(1)
+	if a.units.IsPositive() {
+		unitValue = a.value(prices).Div(a.units)
+	}
+	a.units = a.units.Add(amount.Div(unitValue))
+	a.cash = a.cash.Add(amount)
+	a.contributed = a.contributed.Add(amount)
+	a.flows = append(a.flows, performance.CashFlow{When: t, Amount: amount.Neg()})
+}
+
+// buy spends amount of cash on an asset, if it is at least the minimum buy
+func (a *account) buy(t time.Time, asset string, amount, price decimal.Decimal) (Buy, bool) {
+	amount = decimal.Min(amount, a.cash)
+	if amount.LessThan(minBuyAmount) {
+		return Buy{}, false
+	}
+
+	quantity := amount.Div(price)
+	a.cash = a.cash.Sub(amount)
+	a.invested = a.invested.Add(amount)
+	a.quantity[asset] = a.quantity[asset].Add(quantity)
+	a.cost[asset] = a.cost[asset].Add(amount)
+	return Buy{Time: t, Asset: asset, Amount: amount, Price: price, Quantity: quantity}, true
+}
+
+// mark updates the drawdown of the unit value at the given prices
+func (a *account) mark(prices map[string]decimal.Decimal) {
+	if !a.units.IsPositive() {
+		return
+	}
+	unitValue := a.value(prices).Div(a.units)
+	a.peak = decimal.Max(a.peak, unitValue)
+	if a.peak.IsPositive() {
+		a.maxDrawdown = decimal.Max(a.maxDrawdown, a.peak.Sub(unitValue).Div(a.peak).Mul(hundred))
+	}
+}
+
+// finish values the account at the final prices and returns its final value, IRR, max drawdown
+// and average cost per asset
+func (a *account) finish(end time.Time, prices map[string]decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal, map[string]decimal.Decimal) {
+	a.mark(prices)
+	value := a.value(prices)
+
+	irr := decimal.Zero
+	if value.IsPositive() {
+		flows := append(append([]performance.CashFlow{}, a.flows...), performance.CashFlow{When: end, Amount: value})
+		if rate, ok := performance.IRR(flows); ok {
+			irr = rate
+		}
+	}
+
+	averageCost := make(map[string]decimal.Decimal)
+	for asset, quantity := range a.quantity {
+		if quantity.IsPositive() {
+			averageCost[asset] = a.cost[asset].Div(quantity).Round(8)
+		}
+	}
+
+	return value.Round(2), irr, a.maxDrawdown.Round(2), averageCost
+}
+
+// costVsDCA returns how much more of each asset a USDC bought than under plain DCA, in percent,
+// weighted by allocation
+func costVsDCA(params Params, averageCost, dcaAverageCost map[string]decimal.Decimal) decimal.Decimal {
+	total, weighted := decimal.Zero, decimal.Zero
+	for _, asset := range params.Assets() {
+		cost, dcaCost := averageCost[asset], dcaAverageCost[asset]
+		if !cost.IsPositive() || !dcaCost.IsPositive() {
+			continue
+		}
+		edge := dcaCost.Div(cost).Sub(decimal.NewFromInt(1)).Mul(hundred)
+		weighted = weighted.Add(edge.Mul(params.Allocations[asset]))
+		total = total.Add(params.Allocations[asset])
+	}
+	if !total.IsPositive() {
+		return decimal.Zero
+	}
+	return weighted.Div(total).Round(2)
+}
+
+// day truncates a time to its UTC day
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// later returns the later of two times, treating the zero time as unset
+func later(a, b time.Time) time.Time {
+	if a.IsZero() || b.After(a) {
+		return b
+	}
+	return a
+}
+
+// earlier returns the earlier of two times, treating the zero time as unset
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,169 @@
+package backtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	march4 = time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	march5 = march4.AddDate(0, 0, 1)
+	march6 = march4.AddDate(0, 0, 2)
+	march7 = march4.AddDate(0, 0, 3)
+
+	// Four days of BTC opening and closing at the same price, with the Fear & Greed Index in
+	// extreme fear on the second day and extreme greed otherwise
+	testData = NewData(
+		map[string][]types.Candle{"BTC": {
+			{Start: march4, Open: decimal.NewFromInt(100), Close: decimal.NewFromInt(100)},
+			{Start: march5, Open: decimal.NewFromInt(50), Close: decimal.NewFromInt(50)},
+			{Start: march6, Open: decimal.NewFromInt(100), Close: decimal.NewFromInt(100)},
+			{Start: march7, Open: decimal.NewFromInt(200), Close: decimal.NewFromInt(200)},
+		}},
+		[]types.FearGreedIndex{
+			{Value: 100, Timestamp: march4},
+			{Value: 0, Timestamp: march5},
+			{Value: 100, Timestamp: march6},
+			{Value: 100, Timestamp: march7},
+		},
+	)
+)
+
+func TestDataRange(t *testing.T) {
+	start, end := testData.Range()
+	if !start.Equal(march4) || !end.Equal(march4.AddDate(0, 0, 4)) {
+		t.Errorf("range = %s to %s, want %s to %s", start, end, march4, march4.AddDate(0, 0, 4))
+	}
+	if value, ok := testData.FNG(march5.Add(12 * time.Hour)); !ok || value != 0 {
+		t.Errorf("FNG during March 5 = %d, %v, want 0", value, ok)
+	}
+}
+
+func TestRunFlat(t *testing.T) {
+	start, end := testData.Range()
+	result, err := Run(testData, Params{
+		Frequency:       "daily",
+		BaseInvestment:  decimal.NewFromInt(70), // 10 USDC a day
+		MultiplierCurve: CurveFlat,
+		MinMultiplier:   decimal.NewFromInt(1),
+		MaxMultiplier:   decimal.NewFromInt(1),
+		BufferCurve:     BufferNone,
+		Allocations:     map[string]decimal.Decimal{"BTC": decimal.NewFromInt(100)},
+	}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Buys 0.1, 0.2, 0.1 and 0.05 BTC for 10 USDC each, the same as plain DCA
+	if result.Periods != 4 || len(result.Buys) != 4 || len(result.Curve) != 4 {
+		t.Errorf("got %d periods, %d buys and %d curve points, want 4 each", result.Periods, len(result.Buys), len(result.Curve))
+	}
+	if !result.Contributed.Equal(decimal.NewFromInt(40)) || !result.Invested.Equal(decimal.NewFromInt(40)) || !result.Cash.IsZero() {
+		t.Errorf("contributed %s, invested %s, cash %s, want 40, 40 and 0", result.Contributed, result.Invested, result.Cash)
+	}
+	if !result.FinalValue.Equal(decimal.NewFromInt(90)) {
+		t.Errorf("final value = %s, want 90", result.FinalValue)
+	}
+	if got := result.AverageCost["BTC"]; !got.Equal(decimal.RequireFromString("88.88888889")) {
+		t.Errorf("average cost = %s, want 88.88888889", got)
+	}
+	if !result.CostVsDCA.IsZero() || !result.DCA.FinalValue.Equal(result.FinalValue) {
+		t.Errorf("cost vs DCA %s, DCA final value %s, want 0 and %s", result.CostVsDCA, result.DCA.FinalValue, result.FinalValue)
+	}
+}
+
+func TestRunLinear(t *testing.T) {
+	start, end := testData.Range()
+	result, err := Run(testData, Params{
+		Frequency:       "daily",
+		BaseInvestment:  decimal.NewFromInt(70),
+		MultiplierCurve: CurveLinear,
+		MinMultiplier:   decimal.NewFromInt(1),
+		MaxMultiplier:   decimal.NewFromInt(3),
+		BufferCurve:     BufferLinear,
+		MaxBuffer:       decimal.NewFromInt(50),
+		Allocations:     map[string]decimal.Decimal{"BTC": decimal.NewFromInt(100)},
+	}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Greed days hold half the cash back, which the fear day spends at half the price
+	if !result.Invested.Equal(decimal.RequireFromString("32.5")) || !result.Cash.Equal(decimal.RequireFromString("7.5")) {
+		t.Errorf("invested %s, cash %s, want 32.5 and 7.5", result.Invested, result.Cash)
+	}
+	if !result.FinalValue.Equal(decimal.NewFromInt(95)) || !result.DCA.FinalValue.Equal(decimal.NewFromInt(90)) {
+		t.Errorf("final value %s, DCA final value %s, want 95 and 90", result.FinalValue, result.DCA.FinalValue)
+	}
+	if got := result.AverageCost["BTC"]; !got.Equal(decimal.RequireFromString("74.28571429")) {
+		t.Errorf("average cost = %s, want 74.28571429", got)
+	}
+	if !result.CostVsDCA.Equal(decimal.RequireFromString("19.66")) {
+		t.Errorf("cost vs DCA = %s, want 19.66", result.CostVsDCA)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	start, end := testData.Range()
+	params := Params{
+		Frequency:       "daily",
+		BaseInvestment:  decimal.NewFromInt(70),
+		MultiplierCurve: CurveFlat,
+		MinMultiplier:   decimal.NewFromInt(1),
+		MaxMultiplier:   decimal.NewFromInt(1),
+		BufferCurve:     BufferNone,
+		Allocations:     map[string]decimal.Decimal{"BTC": decimal.NewFromInt(90)},
+	}
+	if _, err := Run(testData, params, start, end); err == nil {
+		t.Error("Run with allocations below 100 succeeded, want an error")
+	}
+
+	params.Allocations = map[string]decimal.Decimal{"BTC": decimal.NewFromInt(100)}
+	if _, err := Run(testData, params, end, end.AddDate(0, 0, 7)); err == nil {
+		t.Error("Run outside the data succeeded, want an error")
+	}
+
+	params.Allocations = map[string]decimal.Decimal{"ETH": decimal.NewFromInt(100)}
+	if _, err := Run(testData, params, start, end); err == nil {
+		t.Error("Run without prices for the asset succeeded, want an error")
+	}
+}
+
+func TestSweepSkipsFailedRuns(t *testing.T) {
+	btc := Params{
+		Frequency:       "daily",
+		BaseInvestment:  decimal.NewFromInt(70),
+		MultiplierCurve: CurveFlat,
+		MinMultiplier:   decimal.NewFromInt(1),
+		MaxMultiplier:   decimal.NewFromInt(1),
+		BufferCurve:     BufferNone,
+		Allocations:     map[string]decimal.Decimal{"BTC": decimal.NewFromInt(100)},
+	}
+	eth := btc
+	eth.Allocations = map[string]decimal.Decimal{"ETH": decimal.NewFromInt(100)}
+
+	start, end := testData.Range()
+	results, skipped, err := Sweep(context.Background(), testData, []Params{btc, eth}, []Window{{Start: start, End: end}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0][0] == nil || results[0][1] != nil {
+		t.Errorf("results = %v, want only the BTC backtest", results[0])
+	}
+	if len(skipped) != 1 || skipped[0].Name != eth.Name() {
+		t.Errorf("skipped = %+v, want the ETH backtest", skipped)
+	}
+
+	ranked, err := Rank(results[0], MetricFinalValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 1 {
+		t.Errorf("ranked %d results, want the skipped backtest left out", len(ranked))
+	}
+}
@@ -0,0 +1,447 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Metrics sweep results are ranked by
+const (
+	MetricFinalValue  = "final_value"
+	MetricIRR         = "irr"
+	MetricMaxDrawdown = "max_drawdown" // Lower is better
+	MetricCostVsDCA   = "cost_vs_dca"
+)
+
+// Grid lists the values to sweep for each parameter. Every combination is simulated; a parameter
+// without values keeps its default.
+type Grid struct {
+	Frequencies      []string                     `json:"frequencies"`
+	BaseInvestments  []decimal.Decimal            `json:"base_investments"`
+	MultiplierCurves []string                     `json:"multiplier_curves"`
+	MinMultipliers   []decimal.Decimal            `json:"min_multipliers"`
+	MaxMultipliers   []decimal.Decimal            `json:"max_multipliers"`
+	BufferCurves     []string                     `json:"buffer_curves"`
+	MaxBuffers       []decimal.Decimal            `json:"max_buffer_percents"`
+	Allocations      []map[string]decimal.Decimal `json:"allocations"`
+}
+
+// ReadGrid parses a parameter grid from JSON
+func ReadGrid(r io.Reader) (*Grid, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var grid Grid
+	if err := decoder.Decode(&grid); err != nil {
+		return nil, fmt.Errorf("failed to parse parameter grid: %w", err)
+	}
+	return &grid, nil
+}
+
+// Params returns every combination of the grid's values, filling parameters without values from
+// defaults. Parameters their curves ignore are normalized, so combinations that only differ in
+// them are listed once.
+func (g *Grid) Params(defaults Params) ([]Params, error) {
+	frequencies := orDefault(g.Frequencies, defaults.Frequency)
+	bases := orDefault(g.BaseInvestments, defaults.BaseInvestment)
+	curves := orDefault(g.MultiplierCurves, defaults.MultiplierCurve)
+	mins := orDefault(g.MinMultipliers, defaults.MinMultiplier)
+	maxes := orDefault(g.MaxMultipliers, defaults.MaxMultiplier)
+	buffers := orDefault(g.BufferCurves, defaults.BufferCurve)
+	maxBuffers := orDefault(g.MaxBuffers, defaults.MaxBuffer)
+	allocations := orDefault(g.Allocations, defaults.Allocations)
+
+	seen := make(map[string]bool)
+	var params []Params
+	for _, frequency := range frequencies {
+		for _, base := range bases {
+			for _, curve := range curves {
+				for _, minimum := range mins {
+					for _, maximum := range maxes {
+						for _, buffer := range buffers {
+							for _, maxBuffer := range maxBuffers {
+								for _, allocation := range allocations {
+									p := Params{
+										Frequency:       strings.ToLower(frequency),
+										BaseInvestment:  base,
+										MultiplierCurve: strings.ToLower(curve),
+										MinMultiplier:   minimum,
+										MaxMultiplier:   maximum,
+										BufferCurve:     strings.ToLower(buffer),
+										MaxBuffer:       maxBuffer,
+										Allocations:     allocation,
+									}
+									p = p.normalize()
+									if err := p.Validate(); err != nil {
+										return nil, fmt.Errorf("invalid parameters %s: %w", p.Name(), err)
+									}
+									if seen[p.Name()] {
+										continue
+									}
+									seen[p.Name()] = true
+									params = append(params, p)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return params, nil
+}
+
+// normalize resets the parameters the curves ignore: the multiplier bounds of the flat curve and
+// the max buffer of buffer curves other than linear
+func (p Params) normalize() Params {
+	if p.MultiplierCurve == CurveFlat {
+		p.MinMultiplier = decimal.NewFromInt(1)
+		p.MaxMultiplier = decimal.NewFromInt(1)
+	}
+	if p.BufferCurve != BufferLinear {
+		p.MaxBuffer = decimal.Zero
+	}
+	return p
+}
+
+// orDefault returns values, or the default alone if there are none
+func orDefault[T any](values []T, fallback T) []T {
+	if len(values) == 0 {
+		return []T{fallback}
+	}
+	return values
+}
+
+// Window is a date range a sweep is run over
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// String returns the window as "YYYY-MM-DD..YYYY-MM-DD"
+func (w Window) String() string {
+	return w.Start.Format("2006-01-02") + ".." + w.End.Format("2006-01-02")
+}
+
+// Windows splits [start, end) into count consecutive windows of whole days and about equal length
+func Windows(start, end time.Time, count int) []Window {
+	days := int(day(end).Sub(day(start)).Hours() / 24)
+	if count < 1 || days < count {
+		return nil
+	}
+
+	windows := make([]Window, count)
+	for i := range windows {
+		windows[i] = Window{
+			Start: day(start).AddDate(0, 0, days*i/count),
+			End:   day(start).AddDate(0, 0, days*(i+1)/count),
+		}
+	}
+	return windows
+}
+
+// Skipped is a backtest of a sweep that failed and is left out of the results
+type Skipped struct {
+	Name   string `json:"name"`
+	Window Window `json:"window"`
+	Error  string `json:"error"`
+}
+
+// Sweep backtests every parameter set over every window on workers goroutines, all CPU cores if
+// workers is zero. The results are indexed by window, then by parameter set. A backtest that fails
+// doesn't stop the sweep: its result is left nil and it is listed as skipped.
+func Sweep(ctx context.Context, data *Data, params []Params, windows []Window, workers int) ([][]*Result, []Skipped, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([][]*Result, len(windows))
+	for w := range windows {
+		results[w] = make([]*Result, len(params))
+	}
+
+	type job struct{ window, param int }
+	jobs := make(chan job)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var skipped []Skipped
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				window := windows[j.window]
+				result, err := Run(data, params[j.param], window.Start, window.End)
+				if err != nil {
+					mu.Lock()
+					skipped = append(skipped, Skipped{Name: params[j.param].Name(), Window: window, Error: err.Error()})
+					mu.Unlock()
+					continue
+				}
+				results[j.window][j.param] = result
+			}
+		}()
+	}
+
+feed:
+	for w := range windows {
+		for p := range params {
+			select {
+			case jobs <- job{w, p}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(skipped, func(i, j int) bool {
+		if !skipped[i].Window.Start.Equal(skipped[j].Window.Start) {
+			return skipped[i].Window.Start.Before(skipped[j].Window.Start)
+		}
+		return skipped[i].Name < skipped[j].Name
+	})
+	return results, skipped, nil
+}
+
+// Rank returns the results ordered best first by metric, ties broken by name. Nil results of
+// skipped backtests are left out.
+func Rank(results []*Result, metric string) ([]*Result, error) {
+	if _, err := metricValue(&Result{}, metric); err != nil {
+		return nil, err
+	}
+
+	var ranked []*Result
+	for _, result := range results {
+		if result != nil {
+			ranked = append(ranked, result)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, _ := metricValue(ranked[i], metric)
+		b, _ := metricValue(ranked[j], metric)
+		if metric == MetricMaxDrawdown {
+			a, b = a.Neg(), b.Neg()
+		}
+		if !a.Equal(b) {
+			return a.GreaterThan(b)
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked, nil
+}
+
+// metricValue returns the value of the metric a result is ranked by
+func metricValue(result *Result, metric string) (decimal.Decimal, error) {
+	switch metric {
+	case MetricFinalValue:
+		return result.FinalValue, nil
+	case MetricIRR:
+		return result.IRR, nil
+	case MetricMaxDrawdown:
+		return result.MaxDrawdown, nil
+	case MetricCostVsDCA:
+		return result.CostVsDCA, nil
+	default:
+		return decimal.Zero, fmt.Errorf("unsupported metric: %s (must be final_value, irr, max_drawdown or cost_vs_dca)", metric)
+	}
+}
+
+// Fold is one walk-forward step: the parameters ranked best over the training window, and how
+// they did over the following test window
+type Fold struct {
+	Fold        int     `json:"fold"`
+	Train       Window  `json:"train"`
+	Test        Window  `json:"test"`
+	Chosen      *Result `json:"chosen"`        // training result of the best parameters
+	OutOfSample *Result `json:"out_of_sample"` // the same parameters over the test window
+	TestRank    int     `json:"test_rank"`     // rank of those parameters among all over the test window
+	Candidates  int     `json:"candidates"`
+}
+
+// WalkForward picks the best parameters by metric in each window and tests them on the next one.
+// A choice that keeps ranking near the top out of sample is less likely to be overfit.
+func WalkForward(results [][]*Result, metric string) ([]Fold, error) {
+	var folds []Fold
+	for w := 0; w+1 < len(results); w++ {
+		train, err := Rank(results[w], metric)
+		if err != nil {
+			return nil, err
+		}
+		test, err := Rank(results[w+1], metric)
+		if err != nil {
+			return nil, err
+		}
+		if len(train) == 0 || len(test) == 0 {
+			continue
+		}
+
+		fold := Fold{
+			Fold:       w + 1,
+			Train:      Window{Start: train[0].Start, End: train[0].End},
+			Test:       Window{Start: test[0].Start, End: test[0].End},
+			Chosen:     train[0],
+			Candidates: len(test),
+		}
+		for rank, result := range test {
+			if result.Name == fold.Chosen.Name {
+				fold.OutOfSample = result
+				fold.TestRank = rank + 1
+			}
+		}
+		folds = append(folds, fold)
+	}
+	return folds, nil
+}
+
+// resultHeader returns the CSV columns of ranked results
+func resultHeader(assets []string) []string {
+	header := []string{"rank", "name", "frequency", "base_investment", "multiplier_curve", "min_multiplier",
+		"max_multiplier", "buffer_curve", "max_buffer_percent", "allocations", "start", "end", "periods",
+		"contributed", "invested", "cash", "final_value", "irr", "max_drawdown", "cost_vs_dca",
+		"dca_final_value", "dca_irr", "dca_max_drawdown"}
+	for _, asset := range assets {
+		header = append(header, "average_cost_"+asset, "dca_average_cost_"+asset)
+	}
+	return header
+}
+
+// resultRecord returns the CSV record of a ranked result
+func resultRecord(rank int, result *Result, assets []string) []string {
+	p := result.Params
+	var allocations []string
+	for _, asset := range p.Assets() {
+		allocations = append(allocations, asset+"="+p.Allocations[asset].String())
+	}
+
+	record := []string{
+		strconv.Itoa(rank),
+		result.Name,
+		p.Frequency,
+		p.BaseInvestment.String(),
+		p.MultiplierCurve,
+		p.MinMultiplier.String(),
+		p.MaxMultiplier.String(),
+		p.BufferCurve,
+		p.MaxBuffer.String(),
+		strings.Join(allocations, ";"),
+		result.Start.Format("2006-01-02"),
+		result.End.Format("2006-01-02"),
+		strconv.Itoa(result.Periods),
+		result.Contributed.StringFixed(2),
+		result.Invested.StringFixed(2),
+		result.Cash.StringFixed(2),
+		result.FinalValue.StringFixed(2),
+		result.IRR.StringFixed(2),
+		result.MaxDrawdown.StringFixed(2),
+		result.CostVsDCA.StringFixed(2),
+		result.DCA.FinalValue.StringFixed(2),
+		result.DCA.IRR.StringFixed(2),
+		result.DCA.MaxDrawdown.StringFixed(2),
+	}
+	for _, asset := range assets {
+		record = append(record, optionalFixed(result.AverageCost, asset), optionalFixed(result.DCA.AverageCost, asset))
+	}
+	return record
+}
+
+// optionalFixed formats a price from the map, or an empty string if it is missing
+func optionalFixed(values map[string]decimal.Decimal, key string) string {
+	value, ok := values[key]
+	if !ok {
+		return ""
+	}
+	return value.StringFixed(2)
+}
+
+// resultAssets returns every asset any of the results bought, sorted
+func resultAssets(results []*Result) []string {
+	seen := make(map[string]bool)
+	var assets []string
+	for _, result := range results {
+		for _, asset := range result.Params.Assets() {
+			if !seen[asset] {
+				seen[asset] = true
+				assets = append(assets, asset)
+			}
+		}
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// WriteCSV writes ranked results as CSV, best first
+func WriteCSV(w io.Writer, ranked []*Result) error {
+	writer := csv.NewWriter(w)
+	assets := resultAssets(ranked)
+
+	if err := writer.Write(resultHeader(assets)); err != nil {
+		return fmt.Errorf("failed to write results header: %w", err)
+	}
+	for i, result := range ranked {
+		if err := writer.Write(resultRecord(i+1, result, assets)); err != nil {
+			return fmt.Errorf("failed to write result %s: %w", result.Name, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteFoldsCSV writes walk-forward folds as CSV, one row per fold
+func WriteFoldsCSV(w io.Writer, folds []Fold, metric string) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"fold", "train_start", "train_end", "test_start", "test_end", "chosen",
+		"train_" + metric, "test_" + metric, "test_rank", "candidates", "test_final_value", "test_dca_final_value"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write folds header: %w", err)
+	}
+
+	for _, fold := range folds {
+		trainValue, err := metricValue(fold.Chosen, metric)
+		if err != nil {
+			return err
+		}
+		record := []string{
+			strconv.Itoa(fold.Fold),
+			fold.Train.Start.Format("2006-01-02"),
+			fold.Train.End.Format("2006-01-02"),
+			fold.Test.Start.Format("2006-01-02"),
+			fold.Test.End.Format("2006-01-02"),
+			fold.Chosen.Name,
+			trainValue.StringFixed(2),
+			"", "", strconv.Itoa(fold.Candidates), "", "",
+		}
+		if fold.OutOfSample != nil {
+			testValue, _ := metricValue(fold.OutOfSample, metric)
+			record[7] = testValue.StringFixed(2)
+			record[8] = strconv.Itoa(fold.TestRank)
+			record[10] = fold.OutOfSample.FinalValue.StringFixed(2)
+			record[11] = fold.OutOfSample.DCA.FinalValue.StringFixed(2)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write fold %d: %w", fold.Fold, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Supported catch-up policies for missed DCA periods
+const (
+	CatchUpSkip    = "skip"     // Missed periods are forgotten
+	CatchUpLumpSum = "lump_sum" // All missed base amounts are invested in the next run
+	CatchUpSpread  = "spread"   // Missed base amounts are spread over the next N runs
+)
+
+// maxMissedPeriods bounds how far back missed periods are enumerated
+const maxMissedPeriods = 366
+
+// catchUpPlan is the catch-up work scheduled for the current run
+type catchUpPlan struct {
+	policy  string
+	missed  []time.Time // Periods newly detected as missed during this run
+	periods []time.Time // Periods to catch up in this run
+	pending []time.Time // Periods left for later runs
+	amount  decimal.Decimal
+
+	runsLeft int // Remaining runs to spread pending periods over, after this one
+}
+
+// ValidateCatchUpPolicy validates the catch-up policy configuration
+func ValidateCatchUpPolicy(config *types.BotConfig) error {
+	switch normalizeCatchUpPolicy(config.CatchUpPolicy) {
+	case CatchUpSkip, CatchUpLumpSum:
+		return nil
+	case CatchUpSpread:
+		if config.CatchUpSpreadRuns < 1 {
+			return fmt.Errorf("catch-up spread runs must be at least 1")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported catch-up policy: %s", config.CatchUpPolicy)
+	}
+}
+
+// normalizeCatchUpPolicy lower-cases the policy and defaults it to skip
+func normalizeCatchUpPolicy(policy string) string {
+	if policy == "" {
+		return CatchUpSkip
+	}
+	return strings.ToLower(policy)
+}
+
+// missedPeriods returns the scheduled periods strictly between the last scheduled run and the current one
+func missedPeriods(schedule *Schedule, lastScheduled, current time.Time) []time.Time {
+	if lastScheduled.IsZero() || !current.After(lastScheduled) {
+		return nil
+	}
+
+	var missed []time.Time
+	for t := schedule.Next(lastScheduled); !t.IsZero() && t.Before(current); t = schedule.Next(t) {
+		missed = append(missed, t)
+		if len(missed) >= maxMissedPeriods {
+			break
+		}
+	}
+
+	return missed
+}
+
+// planCatchUp detects missed periods and decides how many to catch up in the current run
+func (b *DCABot) planCatchUp(state *types.BotState, current time.Time) *catchUpPlan {
+	plan := &catchUpPlan{
+		policy: normalizeCatchUpPolicy(b.config.CatchUpPolicy),
+		amount: decimal.Zero,
+	}
+	// The value averaging target already grows through missed periods, so there is nothing to catch up
+	if b.valueAveraging() {
+		plan.policy = CatchUpSkip
+		return plan
+	}
+
+	plan.missed = missedPeriods(b.schedule, state.LastScheduledAt, current)
+	if len(plan.missed) > 0 {
+		b.logger.Warn("Detected missed DCA periods",
+			"missed", len(plan.missed), "since", state.LastScheduledAt.Format(time.RFC3339), "policy", plan.policy)
+	}
+
+	pending := append(append([]time.Time{}, state.PendingCatchUp...), plan.missed...)
+	if len(pending) == 0 {
+		return plan
+	}
+
+	switch plan.policy {
+	case CatchUpLumpSum:
+		plan.periods = pending
+
+	case CatchUpSpread:
+		runsLeft := state.CatchUpRunsLeft
+		if len(plan.missed) > 0 || runsLeft < 1 {
+			runsLeft = b.config.CatchUpSpreadRuns
+		}
+		// Ceiling division so that everything is caught up within the remaining runs
+		count := (len(pending) + runsLeft - 1) / runsLeft
+		plan.periods = pending[:count]
+		plan.pending = pending[count:]
+		plan.runsLeft = runsLeft - 1
+
+	default: // Skip
+		return plan
+	}
+
+	plan.amount = b.config.WeeklyBaseInvestment.Mul(decimal.NewFromInt(int64(len(plan.periods))))
+	return plan
+}
+
+// settleCatchUp records how much of the planned catch-up was actually funded.
+// Periods that could not be funded because of risk caps stay pending for a later run.
+func (b *DCABot) settleCatchUp(plan *catchUpPlan, funded decimal.Decimal) *types.CatchUpSummary {
+	if len(plan.missed) == 0 && len(plan.periods) == 0 && len(plan.pending) == 0 {
+		return nil
+	}
+
+	caughtUp := 0
+	if funded.GreaterThan(decimal.Zero) && b.config.WeeklyBaseInvestment.GreaterThan(decimal.Zero) {
+		caughtUp = int(funded.Div(b.config.WeeklyBaseInvestment).IntPart())
+	}
+	if caughtUp > len(plan.periods) {
+		caughtUp = len(plan.periods)
+	}
+
+	summary := &types.CatchUpSummary{
+		Policy:          plan.policy,
+		MissedPeriods:   plan.missed,
+		CaughtUpPeriods: plan.periods[:caughtUp],
+		PendingPeriods:  append(append([]time.Time{}, plan.periods[caughtUp:]...), plan.pending...),
+		Amount:          b.config.WeeklyBaseInvestment.Mul(decimal.NewFromInt(int64(caughtUp))),
+	}
+
+	if caughtUp > 0 {
+		b.logger.Info("Caught up missed periods", "periods", caughtUp, "amount", summary.Amount.String())
+	}
+	if caughtUp < len(plan.periods) {
+		b.logger.Warn("Catch-up limited by risk caps, deferring to a later run", "periods", len(plan.periods)-caughtUp)
+	}
+	if plan.policy == CatchUpSkip && len(plan.missed) > 0 {
+		b.logger.Info("Skipping missed periods per catch-up policy", "missed", len(plan.missed))
+	}
+
+	return summary
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// days returns the day of month of each time, for compact comparisons
+func days(times []time.Time) []int {
+	result := []int{}
+	for _, t := range times {
+		result = append(result, t.Day())
+	}
+	return result
+}
+
+func equalDays(times []time.Time, want ...int) bool {
+	got := days(times)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMissedPeriods(t *testing.T) {
+	schedule, err := NewSchedule(&types.BotConfig{InvestmentFrequency: FrequencyDaily, ExecutionTime: "00:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	if missed := missedPeriods(schedule, time.Time{}, current); len(missed) != 0 {
+		t.Errorf("first run missed %v, want nothing", missed)
+	}
+	if missed := missedPeriods(schedule, current.AddDate(0, 0, -1), current); len(missed) != 0 {
+		t.Errorf("run after the previous period missed %v, want nothing", missed)
+	}
+	if missed := missedPeriods(schedule, current.AddDate(0, 0, -4), current); !equalDays(missed, 2, 3, 4) {
+		t.Errorf("missed days %v, want [2 3 4]", days(missed))
+	}
+	if missed := missedPeriods(schedule, current.AddDate(-4, 0, 0), current); len(missed) != maxMissedPeriods {
+		t.Errorf("missed %d periods after four years, want the cap of %d", len(missed), maxMissedPeriods)
+	}
+}
+
+func TestPlanCatchUpLumpSum(t *testing.T) {
+	config := &types.BotConfig{
+		InvestmentFrequency:  FrequencyDaily,
+		ExecutionTime:        "00:00",
+		WeeklyBaseInvestment: decimal.NewFromInt(10),
+		CatchUpPolicy:        "LUMP_SUM",
+	}
+	schedule, err := NewSchedule(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewDCABot(config, nil, nil, schedule, nil, nil, nil)
+
+	// Periods left pending by an earlier run are caught up along with the newly missed ones
+	state := &types.BotState{
+		LastScheduledAt: time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC),
+		PendingCatchUp:  []time.Time{time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	plan := b.planCatchUp(state, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC))
+
+	if plan.policy != CatchUpLumpSum {
+		t.Errorf("policy = %s, want %s", plan.policy, CatchUpLumpSum)
+	}
+	if !equalDays(plan.missed, 4) {
+		t.Errorf("missed days %v, want [4]", days(plan.missed))
+	}
+	if !equalDays(plan.periods, 1, 4) || len(plan.pending) != 0 {
+		t.Errorf("periods %v, pending %v, want [1 4] and nothing", days(plan.periods), days(plan.pending))
+	}
+	if !plan.amount.Equal(decimal.NewFromInt(20)) {
+		t.Errorf("amount = %s, want 20", plan.amount)
+	}
+}
+
+func TestPlanCatchUpSkip(t *testing.T) {
+	config := &types.BotConfig{
+		InvestmentFrequency:  FrequencyDaily,
+		ExecutionTime:        "00:00",
+		WeeklyBaseInvestment: decimal.NewFromInt(10),
+	}
+	schedule, err := NewSchedule(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewDCABot(config, nil, nil, schedule, nil, nil, nil)
+
+	state := &types.BotState{LastScheduledAt: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}
+	plan := b.planCatchUp(state, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC))
+
+	if plan.policy != CatchUpSkip {
+		t.Errorf("policy = %s, want %s by default", plan.policy, CatchUpSkip)
+	}
+	if len(plan.missed) != 3 || len(plan.periods) != 0 || !plan.amount.IsZero() {
+		t.Errorf("missed %d, periods %v, amount %s, want 3 missed and nothing to catch up",
+			len(plan.missed), days(plan.periods), plan.amount)
+	}
+}
+
+func TestPlanCatchUpSpread(t *testing.T) {
+	config := &types.BotConfig{
+		InvestmentFrequency:  FrequencyDaily,
+		ExecutionTime:        "00:00",
+		WeeklyBaseInvestment: decimal.NewFromInt(10),
+		CatchUpPolicy:        CatchUpSpread,
+		CatchUpSpreadRuns:    2,
+	}
+	schedule, err := NewSchedule(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewDCABot(config, nil, nil, schedule, nil, nil, nil)
+
+	// Three missed periods over two runs: two now, the last one in the next run
+	state := &types.BotState{LastScheduledAt: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}
+	plan := b.planCatchUp(state, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC))
+	if !equalDays(plan.periods, 2, 3) || !equalDays(plan.pending, 4) || plan.runsLeft != 1 {
+		t.Fatalf("first run: periods %v, pending %v, runs left %d, want [2 3], [4] and 1",
+			days(plan.periods), days(plan.pending), plan.runsLeft)
+	}
+	if !plan.amount.Equal(decimal.NewFromInt(20)) {
+		t.Errorf("first run: amount = %s, want 20", plan.amount)
+	}
+
+	state = &types.BotState{
+		LastScheduledAt: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+		PendingCatchUp:  plan.pending,
+		CatchUpRunsLeft: plan.runsLeft,
+	}
+	plan = b.planCatchUp(state, time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC))
+	if !equalDays(plan.periods, 4) || len(plan.pending) != 0 || plan.runsLeft != 0 {
+		t.Errorf("second run: periods %v, pending %v, runs left %d, want [4], nothing and 0",
+			days(plan.periods), days(plan.pending), plan.runsLeft)
+	}
+
+	// Pending periods without runs left, e.g. after a policy change, start a new spread
+	state = &types.BotState{
+		LastScheduledAt: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+		PendingCatchUp: []time.Time{
+			time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	plan = b.planCatchUp(state, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC))
+	if !equalDays(plan.periods, 1, 2) || !equalDays(plan.pending, 3) || plan.runsLeft != 1 {
+		t.Errorf("restart: periods %v, pending %v, runs left %d, want [1 2], [3] and 1",
+			days(plan.periods), days(plan.pending), plan.runsLeft)
+	}
+}
@@ -1,165 +1,402 @@
 package bot
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"moonshot/notifier"
 	"moonshot/services"
+	"moonshot/store"
 	"moonshot/types"
 
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+// minOrderTimeRemaining is the time that must be left before the run deadline to place another order.
+// Stopping early leaves room to record the run before the process is killed.
+const minOrderTimeRemaining = 15 * time.Second
+
+// RunObserver is called with the kind and outcome of every run, e.g. to export metrics
+type RunObserver func(kind types.RunKind, result *types.ExecutionResult, err error)
+
 // DCABot represents the main DCA bot
 type DCABot struct {
 	config          *types.BotConfig
 	coinbaseService *services.CoinbaseService
 	fngService      *services.FNGService
+	schedule        *Schedule
+	stateStore      store.StateStore
+	ledger          store.Ledger
+	notifier        *notifier.MultiNotifier
 	portfolio       *types.Portfolio
+	logger          *slog.Logger
+	observer        RunObserver
+	priceFeed       PriceFeed
+	candleSource    CandleSource
+	stateLoaded     bool // The run's state was loaded, so saving it can't overwrite a state that failed to load
 }
 
 // NewDCABot creates a new DCA bot instance
-func NewDCABot(config *types.BotConfig, coinbaseService *services.CoinbaseService, fngService *services.FNGService) *DCABot {
+func NewDCABot(config *types.BotConfig, coinbaseService *services.CoinbaseService, fngService *services.FNGService, schedule *Schedule, stateStore store.StateStore, ledger store.Ledger, notifier *notifier.MultiNotifier) *DCABot {
 	return &DCABot{
 		config:          config,
 		coinbaseService: coinbaseService,
 		fngService:      fngService,
+		schedule:        schedule,
+		stateStore:      stateStore,
+		ledger:          ledger,
+		notifier:        notifier,
+		logger:          slog.Default(),
 	}
 }
 
-// Execute runs the main DCA bot logic
-func (b *DCABot) Execute() (*types.ExecutionResult, error) {
-	log.Println("Starting Moonshot DCA bot execution...")
+// runFunc is the body of a run, working on the persisted state
+type runFunc func(ctx context.Context, state *types.BotState) (*types.ExecutionResult, error)
+
+// run wraps every entry point of the bot: it tags the run's log lines with a run ID, counts API
+// retries and loads the state before calling fn, then saves the state, sends the end-of-run
+// notification and reports the outcome to the observer. A state that fails to load is replaced by
+// an empty one for the run, but not saved over the unreadable file.
+func (b *DCABot) run(ctx context.Context, kind types.RunKind, fn runFunc) (*types.ExecutionResult, error) {
+	// Tag every log line of this run so a single execution can be traced end to end
+	runID := uuid.NewString()
+	b.logger = slog.Default().With("run_id", runID)
+
+	// Count API retries made during this run
+	retryStats := services.NewRetryStats()
+	ctx = services.WithRetryStats(ctx, retryStats)
+
+	state, stateErr := b.GetState()
+	if stateErr != nil {
+		b.logger.Warn("Failed to load bot state, continuing without it", "error", stateErr)
+		state = &types.BotState{}
+	}
+	b.stateLoaded = stateErr == nil
 
-	// Get current portfolio
-	portfolio, err := b.coinbaseService.GetPortfolio()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	result, err := fn(ctx, state)
+	if result != nil {
+		result.RunID = runID
+		if retries := retryStats.Counts(); len(retries) > 0 {
+			result.Retries = retries
+		}
+	}
+
+	b.saveState(state)
+	b.notifyRun(result, err)
+	if b.observer != nil {
+		b.observer(kind, result, err)
 	}
-	b.portfolio = portfolio
 
-	// Get Fear & Greed Index
-	fngIndex, err := b.fngService.GetFearGreedIndex()
+	return result, err
+}
+
+// Execute runs the main DCA bot logic. No new orders are placed once the context's deadline is near.
+func (b *DCABot) Execute(ctx context.Context) (*types.ExecutionResult, error) {
+	return b.run(ctx, types.RunKindDCA, b.execute)
+}
+
+// execute is the body of a DCA run
+func (b *DCABot) execute(ctx context.Context, state *types.BotState) (result *types.ExecutionResult, err error) {
+	b.logger.Info("Starting Moonshot DCA bot execution")
+
+	// Work out whether any DCA periods were missed
+	runAt := time.Now()
+	plan := b.planCatchUp(state, b.schedule.Prev(runAt))
+	defer func() {
+		b.recordRun(state, runAt, plan, result, err)
+	}()
+
+	// Settle the previous run's ladder first so its cancelled orders release their funds
+	ladder := b.settleLadder(ctx, state)
+
+	// Gather portfolio, prices and sentiment
+	snapshot, err := b.marketSnapshot(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get FNG index: %w", err)
+		return nil, err
 	}
+	portfolio, fngIndex := snapshot.Portfolio, snapshot.FNGIndex
+	b.addIndicators(ctx, snapshot)
 
-	log.Printf("Current F&G Index: %d (%s), Multiplier: %s",
-		fngIndex.Value, fngIndex.Classification, fngIndex.Multiplier.String())
+	b.logger = b.logger.With("fng", fngIndex.Value)
+	b.logger.Info("Fetched Fear & Greed Index",
+		"classification", fngIndex.Classification,
+		"multiplier", fngIndex.Multiplier.String())
 
-	// Calculate investment decisions (buying only)
-	decisions, err := b.calculateBuyDecisions(fngIndex)
+	// Calculate investment decisions
+	var decisions []types.InvestmentDecision
+	var valueAverage *types.ValueAverageSummary
+	if b.valueAveraging() {
+		decisions, valueAverage, err = b.valueAveragingDecisions(snapshot, state, b.schedule.Prev(runAt))
+	} else {
+		decisions, err = b.calculateBuyDecisions(snapshot, plan.amount)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate investment decisions: %w", err)
 	}
 
+	// Take profit on assets meeting the sell conditions; they aren't bought in the same run
+	var sells []types.InvestmentDecision
+	var averageCosts map[string]decimal.Decimal
+	if b.config.TakeProfitEnabled {
+		sells, averageCosts = b.takeProfitDecisions(snapshot, state, runAt)
+		decisions = append(withoutAssets(decisions, sells), sells...)
+	}
+
 	// Execute decisions
 	executionResult := &types.ExecutionResult{
-		Success:   true,
-		Decisions: decisions,
-		Portfolio: portfolio,
-		FNGIndex:  fngIndex,
-		Timestamp: time.Now(),
+		Success:      true,
+		Decisions:    decisions,
+		Portfolio:    portfolio,
+		FNGIndex:     fngIndex,
+		ValueAverage: valueAverage,
+		Timestamp:    time.Now(),
 	}
+	executionResult.BufferPercent = b.calculateDynamicBuffer(fngIndex.Value).Mul(decimal.NewFromInt(100))
 
 	totalInvested := decimal.Zero
+	twapCommitted := decimal.Zero
 	successfulOrders := 0
 	failedOrders := 0
 
+	// Sells go first so their proceeds are back in USDC before buying
+	b.executeSells(ctx, sells, averageCosts, state, executionResult)
+
 	for _, decision := range decisions {
 		if decision.Action == "buy" {
-			if err := b.executeBuyOrder(decision); err != nil {
-				log.Printf("❌ Failed to execute buy order: %v", err)
+			// Large buys are split into TWAP child orders, executed after the other orders
+			if b.useTWAP(decision) {
+				b.startTWAP(decision, state)
+				twapCommitted = twapCommitted.Add(decision.Amount)
+
+				// Persist the parent with its period, so a restart continues it instead of buying the period again
+				state.LastScheduledAt = b.schedule.Prev(runAt)
+				b.saveState(state)
+				continue
+			}
+
+			order := types.OrderResult{Asset: decision.Asset, Side: "BUY", Amount: decision.Amount}
+
+			// Don't start an order that may not finish before the deadline
+			if err := checkDeadline(ctx); err != nil {
+				b.logger.Warn("Skipping order", "asset", decision.Asset, "error", err)
 				executionResult.Success = false
 				executionResult.Error = err.Error()
+				order.Error = err.Error()
+				executionResult.Orders = append(executionResult.Orders, order)
+				failedOrders++
+				continue
+			}
+
+			if err := b.executeBuyOrder(ctx, decision, &order); err != nil {
+				b.logger.Error("Failed to execute buy order", "asset", decision.Asset, "error", err)
+				b.notify(notifier.Event{Type: notifier.EventOrderFailed, Decision: &decision, Error: err.Error()})
+				executionResult.Success = false
+				executionResult.Error = err.Error()
+				order.Error = err.Error()
 				failedOrders++
 			} else {
-				totalInvested = totalInvested.Add(decision.Amount)
+				totalInvested = totalInvested.Add(order.Amount)
+				executionResult.FeeSavings = executionResult.FeeSavings.Add(order.FeeSavings)
+				order.Success = true
 				successfulOrders++
 			}
+			executionResult.Orders = append(executionResult.Orders, order)
+		}
+	}
+
+	// Replace the ladder with new rungs below market
+	if b.config.LadderEnabled {
+		b.placeLadder(ctx, snapshot, totalInvested.Add(twapCommitted), state, ladder)
+	}
+	if b.config.LadderEnabled || ladder.Filled.IsPositive() || ladder.Cancelled > 0 {
+		executionResult.Ladder = ladder
+	}
+
+	// TWAP parents count as invested for this period even if their children run later
+	committed := totalInvested.Add(twapCommitted)
+
+	// Whatever is left of the buffer is the reserve for dip buys until the next run
+	state.DipReserve = b.dipReserve(fngIndex.Value, committed, ladder.Budget)
+
+	// Execute the TWAP child orders due now, including those left by earlier runs
+	twapSummaries, twapOrders := b.runTWAP(ctx, state)
+	executionResult.TWAP = twapSummaries
+	for _, order := range twapOrders {
+		if order.Success {
+			totalInvested = totalInvested.Add(order.Amount)
+			executionResult.FeeSavings = executionResult.FeeSavings.Add(order.FeeSavings)
+			successfulOrders++
+		} else {
+			executionResult.Success = false
+			executionResult.Error = order.Error
+			failedOrders++
 		}
+		executionResult.Orders = append(executionResult.Orders, order)
 	}
 
 	// Log execution summary
 	if successfulOrders > 0 {
-		log.Printf("✅ Successfully placed %d orders, total invested: $%s USD",
-			successfulOrders, totalInvested.String())
+		b.logger.Info("Placed orders", "orders", successfulOrders, "total_invested", totalInvested.String())
 	}
 	if failedOrders > 0 {
-		log.Printf("❌ Failed to place %d orders", failedOrders)
+		b.logger.Error("Failed to place orders", "orders", failedOrders)
 	}
 
 	executionResult.TotalInvested = totalInvested
-	executionResult.TotalSold = decimal.Zero // No selling
 
-	log.Printf("Execution completed. Invested: %s USDC", totalInvested.String())
+	// Whatever was invested beyond the regular amount went towards missed periods
+	if !b.valueAveraging() {
+		executionResult.CatchUp = b.settleCatchUp(plan, committed.Sub(b.regularInvestment(snapshot)))
+	}
+
+	// Report cost basis and returns from the trade ledger
+	executionResult.Performance = b.performanceReport(decisions)
+
+	b.logger.Info("Execution completed",
+		"success", executionResult.Success,
+		"total_invested", totalInvested.String(),
+		"total_sold", executionResult.TotalSold.String())
 
 	return executionResult, nil
 }
 
-// calculateBuyDecisions calculates buy orders based on F&G index
-func (b *DCABot) calculateBuyDecisions(fngIndex *types.FearGreedIndex) ([]types.InvestmentDecision, error) {
+// calculateBuyDecisions calculates buy orders from the weighted F&G and indicator signals plus any catch-up amount for missed periods
+func (b *DCABot) calculateBuyDecisions(snapshot *types.MarketSnapshot, catchUpAmount decimal.Decimal) ([]types.InvestmentDecision, error) {
 	var decisions []types.InvestmentDecision
+	fngIndex := snapshot.FNGIndex
 
-	// Calculate available investment amount
-	availableUSDC := b.portfolio.USDCBalance
 	baseInvestment := b.config.WeeklyBaseInvestment
+	hundred := decimal.NewFromInt(100)
+	allocations := b.allocations()
+
+	// Apply each asset's multiplier to its share of the base investment; catch-up base amounts are added unscaled
+	multipliers := make(map[string]decimal.Decimal)
+	signals := make(map[string]map[string]decimal.Decimal)
+	volatility := make(map[string]*types.VolatilityAdjustment)
+	targets := make(map[string]decimal.Decimal)
+	for _, asset := range b.configuredAssets() {
+		multipliers[asset], signals[asset], volatility[asset] = b.sizedMultiplier(snapshot, asset)
+		targets[asset] = baseInvestment.Mul(multipliers[asset]).Add(catchUpAmount).Mul(allocations[asset]).Div(hundred)
+	}
+
+	amounts := b.capInvestment(snapshot, targets)
+	if amounts == nil {
+		return decisions, nil
+	}
 
-	// Apply F&G multiplier to determine investment amount
-	investmentAmount := baseInvestment.Mul(fngIndex.Multiplier)
+	// Create buy decisions at the snapshot prices; assets that couldn't be priced are skipped
+	for _, asset := range b.configuredAssets() {
+		price, ok := snapshot.Prices[asset]
+		if !ok || !amounts[asset].GreaterThan(decimal.Zero) {
+			continue
+		}
+
+		values := snapshot.Indicators[asset]
+		reason := fmt.Sprintf("DCA with F&G multiplier %s (Index: %d)", fngIndex.Multiplier.String(), fngIndex.Value)
+		if _, ok := signals[asset][SignalFNG]; len(signals[asset]) > 1 || !ok || volatility[asset] != nil {
+			reason = signalReason(multipliers[asset], fngIndex, signals[asset], values)
+		}
+		if adjustment := volatility[asset]; adjustment != nil {
+			reason += volatilityReason(adjustment)
+		}
+		if catchUpAmount.GreaterThan(decimal.Zero) {
+			reason += fmt.Sprintf(", including %s USDC catch-up for missed periods", catchUpAmount.String())
+		}
+
+		decisions = append(decisions, types.InvestmentDecision{
+			Asset:      asset,
+			Action:     "buy",
+			Amount:     amounts[asset],
+			Price:      price,
+			Reason:     reason,
+			Timestamp:  time.Now(),
+			Multiplier: multipliers[asset],
+			Indicators: values,
+			Signals:    signals[asset],
+			Volatility: volatility[asset],
+		})
+	}
+
+	return decisions, nil
+}
+
+// capInvestment applies the per-run investment cap and the dynamic buffer to the amount each asset
+// should be bought for, scaling every asset down evenly. Returns nil if there is nothing to invest.
+func (b *DCABot) capInvestment(snapshot *types.MarketSnapshot, targets map[string]decimal.Decimal) map[string]decimal.Decimal {
+	availableUSDC := b.portfolio.USDCBalance
+
+	investmentAmount := decimal.Zero
+	for _, target := range targets {
+		investmentAmount = investmentAmount.Add(target)
+	}
+	requestedAmount := investmentAmount
+
+	// Respect the per-run investment cap, if configured
+	if b.config.MaxDailyInvestment.GreaterThan(decimal.Zero) && investmentAmount.GreaterThan(b.config.MaxDailyInvestment) {
+		b.logger.Warn("Investment capped by max daily investment",
+			"requested", investmentAmount.String(), "max_daily_investment", b.config.MaxDailyInvestment.String())
+		b.notify(notifier.Event{
+			Type: notifier.EventRiskBlocked,
+			Reason: fmt.Sprintf("Investment of %s USDC capped at the max daily investment of %s USDC",
+				investmentAmount.StringFixed(2), b.config.MaxDailyInvestment.StringFixed(2)),
+		})
+		investmentAmount = b.config.MaxDailyInvestment
+	}
 
 	// Calculate dynamic buffer based on market sentiment
-	dynamicBuffer := b.calculateDynamicBuffer(fngIndex.Value)
+	dynamicBuffer := b.calculateDynamicBuffer(snapshot.FNGIndex.Value)
 
 	// Ensure we don't exceed available USDC (leave dynamic buffer for dip buying)
 	maxInvestment := availableUSDC.Mul(decimal.NewFromFloat(1.0).Sub(dynamicBuffer))
 	if investmentAmount.GreaterThan(maxInvestment) {
+		if maxInvestment.GreaterThan(decimal.Zero) {
+			b.notify(notifier.Event{
+				Type: notifier.EventRiskBlocked,
+				Reason: fmt.Sprintf("Investment of %s USDC reduced to %s USDC to keep a %s%% dip buying buffer",
+					investmentAmount.StringFixed(2), maxInvestment.StringFixed(2), dynamicBuffer.Mul(decimal.NewFromInt(100)).StringFixed(0)),
+			})
+		}
 		investmentAmount = maxInvestment
 	}
 
 	if investmentAmount.LessThanOrEqual(decimal.Zero) {
-		log.Println("No USDC available for investment")
-		return decisions, nil
-	}
-
-	// Calculate allocations for BTC and ETH
-	btcInvestment := investmentAmount.Mul(b.config.BTCAllocation).Div(decimal.NewFromInt(100))
-	ethInvestment := investmentAmount.Mul(b.config.ETHAllocation).Div(decimal.NewFromInt(100))
-
-	// Get current prices and create buy decisions
-	btcPrice, err := b.getAssetPrice("BTC")
-	if err == nil && btcInvestment.GreaterThan(decimal.Zero) {
-		decisions = append(decisions, types.InvestmentDecision{
-			Asset:     "BTC",
-			Action:    "buy",
-			Amount:    btcInvestment,
-			Price:     btcPrice,
-			Reason:    fmt.Sprintf("DCA with F&G multiplier %s (Index: %d)", fngIndex.Multiplier.String(), fngIndex.Value),
-			Timestamp: time.Now(),
+		b.logger.Warn("No USDC available for investment", "usdc_balance", availableUSDC.String())
+		b.notify(notifier.Event{
+			Type:   notifier.EventRunSkipped,
+			Reason: fmt.Sprintf("No USDC available for investment (balance: %s USDC)", availableUSDC.StringFixed(2)),
 		})
+		return nil
 	}
 
-	ethPrice, err := b.getAssetPrice("ETH")
-	if err == nil && ethInvestment.GreaterThan(decimal.Zero) {
-		decisions = append(decisions, types.InvestmentDecision{
-			Asset:     "ETH",
-			Action:    "buy",
-			Amount:    ethInvestment,
-			Price:     ethPrice,
-			Reason:    fmt.Sprintf("DCA with F&G multiplier %s (Index: %d)", fngIndex.Multiplier.String(), fngIndex.Value),
-			Timestamp: time.Now(),
-		})
+	// Scale every asset down evenly when the total was capped
+	amounts := make(map[string]decimal.Decimal)
+	for asset, target := range targets {
+		amounts[asset] = target
+		if !investmentAmount.Equal(requestedAmount) {
+			amounts[asset] = target.Mul(investmentAmount).Div(requestedAmount)
+		}
 	}
 
-	log.Printf("Investment decisions: BTC: %s USDC, ETH: %s USDC", btcInvestment.String(), ethInvestment.String())
-	log.Printf("Dynamic buffer: %s%% (F&G: %d)", dynamicBuffer.Mul(decimal.NewFromInt(100)).String(), fngIndex.Value)
+	args := make([]any, 0, 2*len(amounts)+2)
+	for _, asset := range sortedKeys(amounts) {
+		args = append(args, asset, amounts[asset].String())
+	}
+	args = append(args, "buffer_percent", dynamicBuffer.Mul(decimal.NewFromInt(100)).String())
+	b.logger.Info("Calculated investment decisions", args...)
 
-	return decisions, nil
+	return amounts
 }
 
 // calculateDynamicBuffer calculates buffer percentage based on F&G index
 func (b *DCABot) calculateDynamicBuffer(fngValue int) decimal.Decimal {
+	return DynamicBuffer(fngValue)
+}
+
+// DynamicBuffer returns the share of the USDC balance held back for dips at an F&G value
+func DynamicBuffer(fngValue int) decimal.Decimal {
 	// F&G ranges from 0-100
 	switch {
 	case fngValue <= 20: // Extreme Fear
@@ -186,60 +423,138 @@ func (b *DCABot) calculateDynamicBuffer(fngValue int) decimal.Decimal {
 	}
 }
 
-// executeBuyOrder executes a buy order
-func (b *DCABot) executeBuyOrder(decision types.InvestmentDecision) error {
-	productID := decision.Asset + "-USDC"
-
+// executeBuyOrder executes a buy order using the configured execution mode, recording the order in result
+func (b *DCABot) executeBuyOrder(ctx context.Context, decision types.InvestmentDecision, result *types.OrderResult) error {
 	// Check if we have sufficient USDC balance
 	if b.portfolio.USDCBalance.LessThan(decision.Amount) {
+		b.notify(notifier.Event{
+			Type:     notifier.EventRiskBlocked,
+			Decision: &decision,
+			Reason:   fmt.Sprintf("Order blocked: insufficient USDC balance for %s %s", decision.Asset, decision.Amount.StringFixed(2)),
+		})
 		return fmt.Errorf("insufficient USDC balance: have %s, need %s",
 			b.portfolio.USDCBalance.String(), decision.Amount.String())
 	}
 
-	// Calculate size in asset units
-	size := decision.Amount.Div(decision.Price)
+	// Check the market hasn't moved away from the decision price
+	if err := b.checkPriceDeviation(decision); err != nil {
+		return err
+	}
+
+	if b.config.ExecutionMode == ExecutionModeLimit {
+		return b.executeLimitBuy(ctx, decision, result)
+	}
 
-	// Log the investment details
-	log.Printf("💰 Investing $%s USD in %s (%.6f %s at $%s per %s)",
-		decision.Amount.String(),
-		decision.Asset,
-		size.InexactFloat64(),
-		decision.Asset,
-		decision.Price.String(),
-		decision.Asset)
+	fill, err := b.placeMarketBuy(ctx, decision)
+	if err != nil {
+		return err
+	}
+	result.OrderID = fill.OrderID
+	result.Execution = ExecutionModeMarket
+	result.FilledSize = fill.Quantity
+	result.AveragePrice = fill.Price
+	result.Fee = fill.Fee
+	return nil
+}
+
+// placeMarketBuy places a market buy order and returns its fill as recorded in the ledger
+func (b *DCABot) placeMarketBuy(ctx context.Context, decision types.InvestmentDecision) (types.LedgerEntry, error) {
+	productID := decision.Asset + "-USDC"
 
-	log.Printf("📊 Placing BUY order: %.6f %s at market price", size.InexactFloat64(), decision.Asset)
+	// Market buys are sized in USDC; the size in asset units is only an estimate for the log
+	quoteSize := decision.Amount.RoundDown(2)
+	size := quoteSize.Div(decision.Price)
 
-	orderResp, err := b.coinbaseService.PlaceOrder(productID, "BUY", "market", size.String(), "")
+	// Log the investment details
+	logger := b.logger.With("asset", decision.Asset)
+	logger.Info("Placing market buy order",
+		"amount", quoteSize.String(),
+		"size", size.StringFixed(6),
+		"price", decision.Price.String())
+
+	orderResp, err := b.coinbaseService.PlaceOrder(ctx, productID, "BUY", "market", quoteSize.String(), "")
 	if err != nil {
-		return fmt.Errorf("failed to place order: %w", err)
+		return types.LedgerEntry{}, fmt.Errorf("failed to place order: %w", err)
 	}
 
 	// Log order result
-	if orderResp.Success {
-		log.Printf("✅ Order placed successfully! Order ID: %s", orderResp.OrderId)
-	} else {
-		log.Printf("❌ Order failed: %s", orderResp.FailureReason)
-		return fmt.Errorf("order failed: %s", orderResp.FailureReason)
+	if !orderResp.Success {
+		logger.Error("Order failed", "reason", orderResp.FailureReason)
+		return types.LedgerEntry{}, fmt.Errorf("order failed: %s", orderResp.FailureReason)
 	}
 
-	return nil
+	logger.Info("Order placed", "order_id", orderResp.OrderId)
+	return b.recordFill(ctx, decision, orderResp.OrderId, ledgerTag(decision)), nil
 }
 
-// getAssetPrice gets the current price of an asset
-func (b *DCABot) getAssetPrice(symbol string) (decimal.Decimal, error) {
-	productID := symbol + "-USDC"
-	product, err := b.coinbaseService.GetProduct(productID)
-	if err != nil {
-		return decimal.Zero, err
+// configuredAssets returns the assets the bot invests in
+func (b *DCABot) configuredAssets() []string {
+	return []string{"BTC", "ETH"}
+}
+
+// allocations returns the configured allocation percentage of each asset
+func (b *DCABot) allocations() map[string]decimal.Decimal {
+	return map[string]decimal.Decimal{
+		"BTC": b.config.BTCAllocation,
+		"ETH": b.config.ETHAllocation,
 	}
+}
 
-	price, err := decimal.NewFromString(product.Price)
+// getPortfolio refreshes the portfolio, valuing every configured asset. Assets that can't be valued
+// are logged, and fail the run in strict valuation mode.
+func (b *DCABot) getPortfolio(ctx context.Context) (*types.Portfolio, error) {
+	portfolio, err := b.coinbaseService.GetPortfolio(ctx, b.configuredAssets())
 	if err != nil {
-		return decimal.Zero, err
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
 	}
 
-	return price, nil
+	for _, symbol := range b.configuredAssets() {
+		asset := portfolio.Assets[symbol]
+		if asset.Status == services.ValuationOK {
+			continue
+		}
+		if b.config.StrictValuation {
+			return nil, fmt.Errorf("failed to value %s: %s", symbol, asset.Error)
+		}
+		b.logger.Warn("Failed to value asset, excluding it from the portfolio value", "asset", symbol, "error", asset.Error)
+	}
+
+	b.portfolio = portfolio
+	return portfolio, nil
+}
+
+// checkDeadline returns an error if the context is done or its deadline is too close to place an order
+func checkDeadline(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("order not placed: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < minOrderTimeRemaining {
+		return fmt.Errorf("order not placed: run deadline in %s", time.Until(deadline).Round(time.Second))
+	}
+	return nil
+}
+
+// notify sends a notification if notifications are configured
+func (b *DCABot) notify(event notifier.Event) {
+	if b.notifier == nil {
+		return
+	}
+	b.notifier.Send(event)
+}
+
+// notifyRun sends the end-of-run notification
+func (b *DCABot) notifyRun(result *types.ExecutionResult, err error) {
+	switch {
+	case err != nil:
+		b.notify(notifier.Event{Type: notifier.EventRunFailed, Error: err.Error()})
+	case len(result.Decisions) > 0 || len(result.Orders) > 0:
+		b.notify(notifier.Event{Type: notifier.EventExecutionSummary, Result: result})
+	}
+}
+
+// SetRunObserver registers a function called with the kind and outcome of every run
+func (b *DCABot) SetRunObserver(observer RunObserver) {
+	b.observer = observer
 }
 
 // GetPortfolio returns the current portfolio
@@ -251,3 +566,75 @@ func (b *DCABot) GetPortfolio() *types.Portfolio {
 func (b *DCABot) GetConfig() *types.BotConfig {
 	return b.config
 }
+
+// GetSchedule returns the bot schedule
+func (b *DCABot) GetSchedule() *Schedule {
+	return b.schedule
+}
+
+// StatefulFeatures returns the enabled features that stop working correctly if the state is lost
+// between runs, e.g. on a Lambda cold start with the state file in /tmp
+func StatefulFeatures(config *types.BotConfig) []string {
+	var features []string
+	if policy := normalizeCatchUpPolicy(config.CatchUpPolicy); policy != CatchUpSkip {
+		features = append(features, "CATCH_UP_POLICY="+policy)
+	}
+	if config.TWAPSlices > 1 {
+		features = append(features, "TWAP_SLICES")
+	}
+	if config.DipTriggerEnabled {
+		features = append(features, "DIP_TRIGGER_ENABLED")
+	}
+	if normalizeStrategy(config.Strategy) == StrategyValueAveraging {
+		features = append(features, "STRATEGY="+StrategyValueAveraging)
+	}
+	if config.TakeProfitEnabled && config.MaxSellPerPeriod.IsPositive() {
+		features = append(features, "MAX_SELL_PER_PERIOD")
+	}
+	return features
+}
+
+// GetState returns the persisted bot state, or an empty state if no state store is configured
+func (b *DCABot) GetState() (*types.BotState, error) {
+	if b.stateStore == nil {
+		return &types.BotState{}, nil
+	}
+	return b.stateStore.Load()
+}
+
+// saveState persists the state, if a state store is configured and the run's state was loaded.
+// Runs save at the end and after orders that must survive the process being killed.
+func (b *DCABot) saveState(state *types.BotState) {
+	if b.stateStore == nil || !b.stateLoaded {
+		return
+	}
+	if err := b.stateStore.Save(state); err != nil {
+		b.logger.Error("Failed to save bot state", "error", err)
+	}
+}
+
+// recordRun records the outcome of a DCA run in the state
+func (b *DCABot) recordRun(state *types.BotState, runAt time.Time, plan *catchUpPlan, result *types.ExecutionResult, err error) {
+	state.LastRunAt = runAt
+	state.TotalRuns++
+
+	if err != nil {
+		// Leave the period unrecorded so the next run detects it as missed
+		state.LastRunSuccess = false
+		state.LastError = err.Error()
+	} else {
+		state.LastScheduledAt = b.schedule.Prev(runAt)
+		state.LastRunSuccess = result.Success
+		state.LastError = result.Error
+
+		if result.CatchUp != nil && plan.policy != CatchUpSkip {
+			state.PendingCatchUp = result.CatchUp.PendingPeriods
+			state.CatchUpRunsLeft = plan.runsLeft
+			state.LastCaughtUp = result.CatchUp.CaughtUpPeriods
+		} else {
+			state.PendingCatchUp = nil
+			state.CatchUpRunsLeft = 0
+			state.LastCaughtUp = nil
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Trigger is a market condition evaluated on every dip check
+type Trigger interface {
+	// Name identifies the trigger in logs
+	Name() string
+	// Evaluate returns the assets to buy and why, or no assets if the condition isn't met
+	Evaluate(ctx context.Context, snapshot *types.MarketSnapshot) ([]string, string, error)
+}
+
+// drawdownTrigger fires for every asset trading the given percentage below its high over the lookback
+type drawdownTrigger struct {
+	coinbaseService *services.CoinbaseService
+	assets          []string
+	percent         decimal.Decimal
+	lookback        time.Duration
+}
+
+// Name implements Trigger
+func (t *drawdownTrigger) Name() string {
+	return "drawdown"
+}
+
+// Evaluate implements Trigger
+func (t *drawdownTrigger) Evaluate(ctx context.Context, snapshot *types.MarketSnapshot) ([]string, string, error) {
+	// Hourly candles cover up to 14 days within the 350 candle limit, daily candles beyond that
+	granularity := services.GranularityOneHour
+	if t.lookback > 14*24*time.Hour {
+		granularity = services.GranularityOneDay
+	}
+
+	var fired, reasons []string
+	for _, asset := range t.assets {
+		price, ok := snapshot.Prices[asset]
+		if !ok {
+			continue
+		}
+
+		candles, err := t.coinbaseService.GetCandles(ctx, asset+"-USDC", snapshot.Timestamp.Add(-t.lookback), snapshot.Timestamp, granularity)
+		if err != nil {
+			return nil, "", err
+		}
+
+		high := price
+		for _, candle := range candles {
+			high = decimal.Max(high, candle.High)
+		}
+		drawdown := high.Sub(price).Div(high).Mul(decimal.NewFromInt(100))
+		if drawdown.GreaterThanOrEqual(t.percent) {
+			fired = append(fired, asset)
+			reasons = append(reasons, fmt.Sprintf("%s down %s%% from its %s high of %s",
+				asset, drawdown.StringFixed(1), t.lookback.String(), high.StringFixed(2)))
+		}
+	}
+
+	return fired, strings.Join(reasons, ", "), nil
+}
+
+// fngTrigger fires for all assets when the Fear & Greed Index drops below the threshold
+type fngTrigger struct {
+	assets    []string
+	threshold int
+}
+
+// Name implements Trigger
+func (t *fngTrigger) Name() string {
+	return "fng"
+}
+
+// Evaluate implements Trigger
+func (t *fngTrigger) Evaluate(ctx context.Context, snapshot *types.MarketSnapshot) ([]string, string, error) {
+	if snapshot.FNGIndex == nil || snapshot.FNGIndex.Value >= t.threshold {
+		return nil, "", nil
+	}
+	return t.assets, fmt.Sprintf("Fear & Greed Index %d below %d", snapshot.FNGIndex.Value, t.threshold), nil
+}
+
+// ValidateDipTrigger checks the dip trigger configuration
+func ValidateDipTrigger(config *types.BotConfig) error {
+	if !config.DipTriggerEnabled {
+		return nil
+	}
+	if config.DipCheckInterval <= 0 {
+		return fmt.Errorf("dip check interval must be positive")
+	}
+	if config.DipDrawdownPercent.IsNegative() || config.DipDrawdownPercent.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return fmt.Errorf("dip drawdown percent must be between 0 and 100")
+	}
+	if config.DipDrawdownPercent.IsPositive() && config.DipLookback <= 0 {
+		return fmt.Errorf("dip lookback must be positive")
+	}
+	if !config.DipBuyPercent.IsPositive() || config.DipBuyPercent.GreaterThan(decimal.NewFromInt(100)) {
+		return fmt.Errorf("dip buy percent must be between 0 and 100")
+	}
+	if config.DipCooldown < 0 {
+		return fmt.Errorf("dip cooldown cannot be negative")
+	}
+	if config.DipMaxBuysPerPeriod < 1 {
+		return fmt.Errorf("dip max buys per period must be at least 1")
+	}
+	if config.DipMaxAmountPerPeriod.IsNegative() {
+		return fmt.Errorf("dip max amount per period cannot be negative")
+	}
+	return nil
+}
+
+// DipCheckInterval returns how often the daemon checks for dips, or zero if the dip trigger is disabled
+func (b *DCABot) DipCheckInterval() time.Duration {
+	if !b.config.DipTriggerEnabled {
+		return 0
+	}
+	return b.config.DipCheckInterval
+}
+
+// triggers returns the configured dip triggers
+func (b *DCABot) triggers() []Trigger {
+	var triggers []Trigger
+	if b.config.DipDrawdownPercent.IsPositive() {
+		triggers = append(triggers, &drawdownTrigger{
+			coinbaseService: b.coinbaseService,
+			assets:          b.configuredAssets(),
+			percent:         b.config.DipDrawdownPercent,
+			lookback:        b.config.DipLookback,
+		})
+	}
+	if b.config.FNGBuyThreshold > 0 {
+		triggers = append(triggers, &fngTrigger{
+			assets:    b.configuredAssets(),
+			threshold: b.config.FNGBuyThreshold,
+		})
+	}
+	return triggers
+}
+
+// dipReserve returns the part of the dynamic buffer left for dip buys after a run's orders and ladder
+func (b *DCABot) dipReserve(fngValue int, committed, ladderBudget decimal.Decimal) decimal.Decimal {
+	reserve := b.portfolio.USDCBalance.Mul(b.calculateDynamicBuffer(fngValue)).Sub(ladderBudget)
+	reserve = decimal.Min(reserve, b.portfolio.USDCBalance.Sub(committed).Sub(ladderBudget))
+	return decimal.Max(reserve, decimal.Zero)
+}
+
+// dipBlocked returns why no dip buy may be made now, or an empty string if one may
+func (b *DCABot) dipBlocked(state *types.BotState, now time.Time) string {
+	switch {
+	case !state.DipReserve.GreaterThanOrEqual(minOrderAmount):
+		return "no reserve left"
+	case !state.LastDipBuyAt.IsZero() && now.Sub(state.LastDipBuyAt) < b.config.DipCooldown:
+		return fmt.Sprintf("cooling down until %s", state.LastDipBuyAt.Add(b.config.DipCooldown).Format(time.RFC3339))
+	case state.DipBuysInPeriod >= b.config.DipMaxBuysPerPeriod:
+		return "max dip buys for this period reached"
+	case b.config.DipMaxAmountPerPeriod.IsPositive() && state.DipSpentInPeriod.GreaterThanOrEqual(b.config.DipMaxAmountPerPeriod):
+		return "max dip amount for this period reached"
+	default:
+		return ""
+	}
+}
+
+// CheckDips evaluates the dip triggers and, if any fires, buys the triggered assets from the reserve
+// left by the last run. Dip buys are subject to a cooldown and per-period caps, and their fills are
+// tagged as dip buys in the ledger.
+func (b *DCABot) CheckDips(ctx context.Context) (*types.ExecutionResult, error) {
+	return b.run(ctx, types.RunKindDipCheck, b.checkDips)
+}
+
+// checkDips is the body of a dip check
+func (b *DCABot) checkDips(ctx context.Context, state *types.BotState) (*types.ExecutionResult, error) {
+	result := &types.ExecutionResult{
+		Success:   true,
+		Timestamp: time.Now(),
+	}
+
+	// Caps apply per schedule period
+	now := time.Now()
+	if period := b.schedule.Prev(now); !state.DipPeriod.Equal(period) {
+		state.DipPeriod = period
+		state.DipBuysInPeriod = 0
+		state.DipSpentInPeriod = decimal.Zero
+	}
+	if reason := b.dipBlocked(state, now); reason != "" {
+		b.logger.Debug("Dip check skipped", "reason", reason)
+		return result, nil
+	}
+
+	snapshot, err := b.marketSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Portfolio, result.FNGIndex = snapshot.Portfolio, snapshot.FNGIndex
+
+	fired := make(map[string]bool)
+	var reasons []string
+	for _, trigger := range b.triggers() {
+		assets, reason, err := trigger.Evaluate(ctx, snapshot)
+		if err != nil {
+			b.logger.Warn("Failed to evaluate dip trigger", "trigger", trigger.Name(), "error", err)
+			continue
+		}
+		if len(assets) == 0 {
+			continue
+		}
+		b.logger.Info("Dip trigger fired", "trigger", trigger.Name(), "reason", reason)
+		reasons = append(reasons, reason)
+		for _, asset := range assets {
+			fired[asset] = true
+		}
+	}
+	if len(fired) == 0 {
+		b.logger.Debug("No dip trigger fired")
+		return result, nil
+	}
+
+	result.Decisions = b.dipDecisions(snapshot, state, fired, "Dip buy: "+strings.Join(reasons, "; "))
+	for _, decision := range result.Decisions {
+		order := types.OrderResult{Asset: decision.Asset, Side: "BUY", Amount: decision.Amount}
+		err := checkDeadline(ctx)
+		if err == nil {
+			err = b.executeBuyOrder(ctx, decision, &order)
+		}
+		if err != nil {
+			b.logger.Error("Failed to execute dip buy", "asset", decision.Asset, "error", err)
+			result.Success = false
+			result.Error = err.Error()
+			order.Error = err.Error()
+		} else {
+			order.Success = true
+			result.TotalInvested = result.TotalInvested.Add(order.Amount)
+			result.FeeSavings = result.FeeSavings.Add(order.FeeSavings)
+		}
+		result.Orders = append(result.Orders, order)
+	}
+
+	if result.TotalInvested.IsPositive() {
+		state.LastDipBuyAt = now
+		state.DipBuysInPeriod++
+		state.DipSpentInPeriod = state.DipSpentInPeriod.Add(result.TotalInvested)
+		state.DipReserve = decimal.Max(state.DipReserve.Sub(result.TotalInvested), decimal.Zero)
+		b.logger.Info("Dip buy completed",
+			"invested", result.TotalInvested.StringFixed(2),
+			"reserve_left", state.DipReserve.StringFixed(2))
+	}
+
+	return result, nil
+}
+
+// dipDecisions sizes a dip buy from the reserve within the period cap and splits it across the
+// triggered assets by their allocation
+func (b *DCABot) dipDecisions(snapshot *types.MarketSnapshot, state *types.BotState, fired map[string]bool, reason string) []types.InvestmentDecision {
+	hundred := decimal.NewFromInt(100)
+
+	amount := state.DipReserve.Mul(b.config.DipBuyPercent).Div(hundred)
+	if b.config.DipMaxAmountPerPeriod.IsPositive() {
+		amount = decimal.Min(amount, b.config.DipMaxAmountPerPeriod.Sub(state.DipSpentInPeriod))
+	}
+	amount = decimal.Min(amount, b.portfolio.USDCBalance)
+
+	allocations := b.allocations()
+	totalAllocation := decimal.Zero
+	for asset := range fired {
+		totalAllocation = totalAllocation.Add(allocations[asset])
+	}
+	if !totalAllocation.IsPositive() {
+		return nil
+	}
+
+	var decisions []types.InvestmentDecision
+	for _, asset := range b.configuredAssets() {
+		price, ok := snapshot.Prices[asset]
+		if !fired[asset] || !ok {
+			continue
+		}
+		assetAmount := amount.Mul(allocations[asset]).Div(totalAllocation).RoundDown(2)
+		if assetAmount.LessThan(minOrderAmount) {
+			continue
+		}
+		decisions = append(decisions, types.InvestmentDecision{
+			Asset:     asset,
+			Action:    "buy",
+			Amount:    assetAmount,
+			Price:     price,
+			Reason:    reason,
+			Tag:       LedgerTagDip,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return decisions
+}
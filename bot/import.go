@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"moonshot/services"
+	"moonshot/store"
+	"moonshot/types"
+
+	"github.com/coinbase-samples/advanced-trade-sdk-go/model"
+	"github.com/shopspring/decimal"
+)
+
+// LedgerEntries returns all trades recorded in the ledger
+func (b *DCABot) LedgerEntries() ([]types.LedgerEntry, error) {
+	if b.ledger == nil {
+		return nil, fmt.Errorf("no trade ledger configured")
+	}
+	return b.ledger.Entries()
+}
+
+// ImportFills fetches Coinbase fill history for the given products and returns ledger entries
+// for fills not already in the ledger. The returned entries are not written
+// to the ledger; use Backfill to persist them.
+func (b *DCABot) ImportFills(ctx context.Context, productIDs []string, since time.Time) ([]types.LedgerEntry, error) {
+	existing, err := b.LedgerEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.fetchNewFills(ctx, productIDs, since, existing)
+}
+
+// Backfill imports Coinbase fill history for the given products into the ledger.
+// Fills already in the ledger, either by fill ID or as an order the bot recorded itself, are
+// skipped, so re-running is safe and picks up later fills of partially filled orders. Orders the
+// bot could only record as an estimate are replaced by their fills.
+func (b *DCABot) Backfill(ctx context.Context, productIDs []string, since time.Time) ([]types.LedgerEntry, error) {
+	existing, err := b.LedgerEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := b.fetchNewFills(ctx, productIDs, since, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	filledOrders := make(map[string]bool)
+	for _, entry := range entries {
+		filledOrders[entry.OrderID] = true
+	}
+	kept := make([]types.LedgerEntry, 0, len(existing))
+	for _, entry := range existing {
+		if entry.Estimated && entry.ID == entry.OrderID && filledOrders[entry.OrderID] {
+			b.logger.Info("Replacing estimated ledger entry with fills", "asset", entry.Asset, "order_id", entry.OrderID)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	if len(kept) == len(existing) {
+		err = b.ledger.Append(entries...)
+	} else {
+		err = b.ledger.Replace(append(kept, entries...))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write backfilled fills: %w", err)
+	}
+
+	return entries, nil
+}
+
+// fetchNewFills fetches fills and their orders for each product and normalizes the fills that
+// are not yet in the ledger. Fills are attributed to the bot when their order's client order ID
+// carries the bot prefix and to manual trading otherwise.
+func (b *DCABot) fetchNewFills(ctx context.Context, productIDs []string, since time.Time, existing []types.LedgerEntry) ([]types.LedgerEntry, error) {
+	// Imported fills are keyed by fill ID. The bot records its own orders as a single entry keyed
+	// by order ID once they are done, which already covers every fill of the order unless the
+	// entry is only an estimate.
+	knownIDs := make(map[string]bool)
+	recordedOrders := make(map[string]bool)
+	for _, entry := range existing {
+		knownIDs[entry.ID] = true
+		if entry.ID == entry.OrderID && !entry.Estimated {
+			recordedOrders[entry.OrderID] = true
+		}
+	}
+
+	var imported []types.LedgerEntry
+	for _, productID := range productIDs {
+		productID = strings.TrimSpace(productID)
+
+		orders, err := b.coinbaseService.ListOrders(ctx, productID, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch orders for %s: %w", productID, err)
+		}
+		clientOrderIDs := make(map[string]string)
+		for _, order := range orders {
+			clientOrderIDs[order.OrderId] = order.ClientOrderId
+		}
+
+		fills, err := b.coinbaseService.ListFills(ctx, productID, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch fills for %s: %w", productID, err)
+		}
+
+		botFills, manualFills := 0, 0
+		for _, fill := range fills {
+			if knownIDs[fill.EntryId] || recordedOrders[fill.OrderId] {
+				continue
+			}
+
+			entry, err := fillToLedgerEntry(fill)
+			if err != nil {
+				b.logger.Warn("Skipping fill", "fill_id", fill.EntryId, "order_id", fill.OrderId, "error", err)
+				continue
+			}
+
+			entry.ClientOrderID = clientOrderIDs[fill.OrderId]
+			if services.IsBotClientOrderID(entry.ClientOrderID) {
+				entry.Source = store.SourceBot
+				botFills++
+			} else {
+				entry.Source = store.SourceManual
+				manualFills++
+			}
+
+			knownIDs[entry.ID] = true
+			imported = append(imported, entry)
+		}
+
+		b.logger.Info("Fetched new fills", "product_id", productID, "fills", botFills+manualFills, "bot", botFills, "manual", manualFills)
+	}
+
+	return imported, nil
+}
+
+// fillToLedgerEntry normalizes a Coinbase fill into the ledger schema
+func fillToLedgerEntry(fill *model.Fill) (types.LedgerEntry, error) {
+	price, err := decimal.NewFromString(fill.Price)
+	if err != nil || price.IsZero() {
+		return types.LedgerEntry{}, fmt.Errorf("invalid fill price %q", fill.Price)
+	}
+
+	size, err := decimal.NewFromString(fill.Size)
+	if err != nil {
+		return types.LedgerEntry{}, fmt.Errorf("invalid fill size %q", fill.Size)
+	}
+
+	commission := decimal.Zero
+	if fill.Commission != "" {
+		commission, err = decimal.NewFromString(fill.Commission)
+		if err != nil {
+			return types.LedgerEntry{}, fmt.Errorf("invalid fill commission %q", fill.Commission)
+		}
+	}
+
+	// Size is reported in quote currency for orders placed by quote amount
+	quantity := size
+	if fill.SizeInQuote {
+		quantity = size.Div(price)
+	}
+
+	return types.LedgerEntry{
+		ID:          fill.EntryId,
+		OrderID:     fill.OrderId,
+		Asset:       strings.SplitN(fill.ProductId, "-", 2)[0],
+		ProductID:   fill.ProductId,
+		Side:        strings.ToUpper(fill.Side),
+		Quantity:    quantity,
+		Price:       price,
+		QuoteAmount: quantity.Mul(price),
+		Fee:         commission,
+		Timestamp:   fill.TradeTime,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/coinbase-samples/advanced-trade-sdk-go/model"
+	"github.com/shopspring/decimal"
+)
+
+// ValidateLadder checks the ladder configuration
+func ValidateLadder(config *types.BotConfig) error {
+	if !config.LadderEnabled {
+		return nil
+	}
+	if len(config.LadderLevels) == 0 {
+		return fmt.Errorf("ladder requires at least one level")
+	}
+	for _, level := range config.LadderLevels {
+		if !level.IsPositive() || level.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+			return fmt.Errorf("ladder level must be between 0 and 100 percent, got %s", level.String())
+		}
+	}
+	if config.LadderBufferShare.IsNegative() || config.LadderBufferShare.GreaterThan(decimal.NewFromInt(100)) {
+		return fmt.Errorf("ladder buffer share must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+// settleLadder records the fills of the rungs placed by the previous run and cancels whatever is
+// still open. Rungs that can't be settled now are kept in the state for the next run. Open orders
+// the bot placed that the state doesn't know about, e.g. after the state was lost, are found on the
+// exchange and settled the same way.
+func (b *DCABot) settleLadder(ctx context.Context, state *types.BotState) *types.LadderSummary {
+	summary := &types.LadderSummary{}
+	fill := &limitFill{}
+
+	var kept []types.LadderOrder
+	known := make(map[string]bool)
+	for _, rung := range state.LadderOrders {
+		known[rung.OrderID] = true
+		if !b.settleOrder(ctx, rung.OrderID, rung.Asset, LedgerTagLadder, rung.Level.String(), fill, summary) {
+			kept = append(kept, rung)
+		}
+	}
+	state.LadderOrders = kept
+
+	for _, order := range b.orphanedOrders(ctx, known) {
+		// Post-only orders are left by limit execution mode, plain limit orders by the ladder
+		tag := LedgerTagLadder
+		if gtc := order.OrderConfiguration.LimitLimitGtc; gtc != nil && gtc.PostOnly {
+			tag = LedgerTagDCA
+		}
+		b.logger.Warn("Settling open order missing from the bot state",
+			"product_id", order.ProductId, "order_id", order.OrderId, "tag", tag)
+		b.settleOrder(ctx, order.OrderId, strings.SplitN(order.ProductId, "-", 2)[0], tag, "", fill, summary)
+	}
+
+	summary.Filled = fill.value.Add(fill.fees)
+	return summary
+}
+
+// settleOrder cancels an order if it is still open and records its fills in the ledger. It returns
+// false if the order couldn't be checked, cancelled or read back, so it should be settled later.
+func (b *DCABot) settleOrder(ctx context.Context, orderID, asset, tag, level string, fill *limitFill, summary *types.LadderSummary) bool {
+	logger := b.logger.With("asset", asset, "order_id", orderID)
+
+	order, err := b.coinbaseService.GetOrder(ctx, orderID)
+	if err != nil {
+		logger.Warn("Failed to check ladder order, keeping it for the next run", "error", err)
+		return false
+	}
+
+	if !orderDone(order.Status) {
+		if err := b.coinbaseService.CancelOrder(ctx, orderID); err != nil {
+			logger.Warn("Failed to cancel ladder order, keeping it for the next run", "error", err)
+			return false
+		}
+		summary.Cancelled++
+
+		// Fetch the final fills; a rung that can't be read now is recorded by the next run
+		if order, err = b.coinbaseService.GetOrder(ctx, orderID); err != nil || !orderDone(order.Status) {
+			return false
+		}
+	}
+
+	before := fill.value.Add(fill.fees)
+	fill.add(order)
+	if filled := fill.value.Add(fill.fees).Sub(before); filled.IsPositive() {
+		logger.Info("Ladder order filled",
+			"level", level,
+			"filled_size", order.FilledSize,
+			"average_price", order.AverageFilledPrice)
+	}
+	b.recordOrder(asset, order, tag)
+	return true
+}
+
+// orphanedOrders lists the bot's open orders on the configured products that aren't in known
+func (b *DCABot) orphanedOrders(ctx context.Context, known map[string]bool) []*model.Order {
+	var orphaned []*model.Order
+	for _, asset := range b.configuredAssets() {
+		orders, err := b.coinbaseService.ListOpenOrders(ctx, asset+"-USDC")
+		if err != nil {
+			b.logger.Warn("Failed to list open orders", "asset", asset, "error", err)
+			continue
+		}
+		for _, order := range orders {
+			if !known[order.OrderId] && services.IsBotClientOrderID(order.ClientOrderId) {
+				orphaned = append(orphaned, order)
+			}
+		}
+	}
+	return orphaned
+}
+
+// placeLadder places GTC limit buys below market from the dip buying buffer. The budget is the
+// configured share of the buffer, scaled by the Fear & Greed multiplier so more is committed in fear,
+// split across assets by allocation and evenly across the levels.
+func (b *DCABot) placeLadder(ctx context.Context, snapshot *types.MarketSnapshot, invested decimal.Decimal, state *types.BotState, summary *types.LadderSummary) {
+	fngIndex := snapshot.FNGIndex
+	hundred := decimal.NewFromInt(100)
+
+	reserved := b.portfolio.USDCBalance.Mul(b.calculateDynamicBuffer(fngIndex.Value))
+	budget := reserved.Mul(b.config.LadderBufferShare).Div(hundred)
+	if b.config.MaxMultiplier.IsPositive() {
+		budget = decimal.Min(budget.Mul(fngIndex.Multiplier).Div(b.config.MaxMultiplier), budget)
+	}
+	budget = decimal.Min(budget, b.portfolio.USDCBalance.Sub(invested))
+	if !budget.IsPositive() {
+		b.logger.Info("No buffer available for the ladder", "reserved", reserved.StringFixed(2))
+		return
+	}
+	summary.Budget = budget
+
+	allocations := b.allocations()
+	levels := decimal.NewFromInt(int64(len(b.config.LadderLevels)))
+
+	for _, asset := range b.configuredAssets() {
+		price, ok := snapshot.Prices[asset]
+		if !ok || !allocations[asset].IsPositive() {
+			continue
+		}
+		logger := b.logger.With("asset", asset)
+
+		if err := checkDeadline(ctx); err != nil {
+			logger.Warn("Skipping ladder", "error", err)
+			return
+		}
+
+		productID := asset + "-USDC"
+		increments, err := b.productIncrements(ctx, productID)
+		if err != nil {
+			logger.Error("Failed to place ladder", "error", err)
+			continue
+		}
+		priceIncrement, sizeIncrement, minAmount := increments.price, increments.size, increments.minAmount
+
+		rungAmount := budget.Mul(allocations[asset]).Div(hundred).Div(levels)
+		if rungAmount.LessThan(minAmount) {
+			logger.Info("Ladder rungs below the minimum order size, skipping",
+				"rung_amount", rungAmount.StringFixed(2), "min_amount", minAmount.String())
+			continue
+		}
+
+		for _, level := range b.config.LadderLevels {
+			rungPrice := price.Mul(hundred.Sub(level)).Div(hundred).Div(priceIncrement).Floor().Mul(priceIncrement)
+			size := rungAmount.Div(rungPrice).Div(sizeIncrement).Floor().Mul(sizeIncrement)
+			if !size.IsPositive() {
+				continue
+			}
+
+			resp, err := b.coinbaseService.PlaceOrder(ctx, productID, "BUY", "limit", size.String(), rungPrice.String())
+			if err == nil && !resp.Success {
+				err = fmt.Errorf("order failed: %s", resp.FailureReason)
+			}
+			if err != nil {
+				logger.Error("Failed to place ladder order", "level", level.String(), "error", err)
+				continue
+			}
+
+			rung := types.LadderOrder{
+				OrderID:  resp.OrderId,
+				Asset:    asset,
+				Level:    level,
+				Price:    rungPrice,
+				Size:     size,
+				Amount:   size.Mul(rungPrice),
+				PlacedAt: time.Now(),
+			}
+			logger.Info("Placed ladder order",
+				"order_id", rung.OrderID,
+				"level", level.String(),
+				"price", rungPrice.String(),
+				"size", size.String())
+
+			summary.Orders = append(summary.Orders, rung)
+			state.LadderOrders = append(state.LadderOrders, rung)
+		}
+	}
+}
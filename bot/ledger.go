@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"moonshot/performance"
+	"moonshot/services"
+	"moonshot/store"
+	"moonshot/types"
+
+	"github.com/coinbase-samples/advanced-trade-sdk-go/model"
+	"github.com/shopspring/decimal"
+)
+
+// Ledger tags identify why a trade was placed
+const (
+	LedgerTagDCA        = "dca"
+	LedgerTagLadder     = "ladder"
+	LedgerTagDip        = "dip"
+	LedgerTagTakeProfit = "take_profit"
+)
+
+// ledgerTag returns the ledger tag for the fills of a decision
+func ledgerTag(decision types.InvestmentDecision) string {
+	if decision.Tag != "" {
+		return decision.Tag
+	}
+	return LedgerTagDCA
+}
+
+// orderSide returns the exchange order side of a decision
+func orderSide(decision types.InvestmentDecision) string {
+	if decision.Action == "sell" {
+		return "SELL"
+	}
+	return "BUY"
+}
+
+// recordFill looks up the fill details of a placed order and appends them to the trade ledger.
+// If the exchange does not return fill details, the decision's amount and price are recorded as an estimate.
+// The entry is returned even if no ledger is configured.
+func (b *DCABot) recordFill(ctx context.Context, decision types.InvestmentDecision, orderID string, tag string) types.LedgerEntry {
+	entry := types.LedgerEntry{
+		ID:          orderID,
+		OrderID:     orderID,
+		Asset:       decision.Asset,
+		ProductID:   decision.Asset + "-USDC",
+		Side:        orderSide(decision),
+		Quantity:    decision.Amount.Div(decision.Price),
+		Price:       decision.Price,
+		QuoteAmount: decision.Amount,
+		Fee:         decimal.Zero,
+		Timestamp:   time.Now(),
+		Source:      store.SourceBot,
+		Tag:         tag,
+		Estimated:   true,
+	}
+
+	order, err := b.coinbaseService.GetOrder(ctx, orderID)
+	if err != nil {
+		b.logger.Warn("Failed to fetch fill details, recording estimate", "asset", decision.Asset, "order_id", orderID, "error", err)
+	} else {
+		applyOrderFill(&entry, order.ClientOrderId, order.FilledSize, order.AverageFilledPrice, order.TotalFees)
+	}
+
+	if b.ledger == nil {
+		return entry
+	}
+	if err := b.ledger.Append(entry); err != nil {
+		b.logger.Error("Failed to record order in ledger", "asset", decision.Asset, "order_id", orderID, "error", err)
+	}
+	return entry
+}
+
+// recordOrder appends the fill of a finished order to the trade ledger. Orders that didn't fill are skipped.
+func (b *DCABot) recordOrder(asset string, order *model.Order, tag string) {
+	if b.ledger == nil {
+		return
+	}
+
+	entry := types.LedgerEntry{
+		ID:        order.OrderId,
+		OrderID:   order.OrderId,
+		Asset:     asset,
+		ProductID: order.ProductId,
+		Side:      order.Side,
+		Timestamp: time.Now(),
+		Source:    store.SourceBot,
+		Tag:       tag,
+		Estimated: true,
+	}
+	applyOrderFill(&entry, order.ClientOrderId, order.FilledSize, order.AverageFilledPrice, order.TotalFees)
+	if entry.Estimated {
+		return
+	}
+
+	if err := b.ledger.Append(entry); err != nil {
+		b.logger.Error("Failed to record order in ledger", "asset", asset, "order_id", order.OrderId, "error", err)
+	}
+}
+
+// applyOrderFill overwrites the estimated ledger values with the exchange-reported fill
+func applyOrderFill(entry *types.LedgerEntry, clientOrderID, filledSize, averagePrice, totalFees string) {
+	entry.ClientOrderID = clientOrderID
+
+	size, err := decimal.NewFromString(filledSize)
+	if err != nil || size.IsZero() {
+		return
+	}
+	price, err := decimal.NewFromString(averagePrice)
+	if err != nil || price.IsZero() {
+		return
+	}
+
+	entry.Quantity = size
+	entry.Price = price
+	entry.QuoteAmount = size.Mul(price)
+	if fees, err := decimal.NewFromString(totalFees); err == nil {
+		entry.Fee = fees
+	}
+	entry.Estimated = false
+}
+
+// PerformanceReport refreshes the portfolio and computes performance from the ledger at current prices
+func (b *DCABot) PerformanceReport(ctx context.Context) (*types.PerformanceReport, error) {
+	if b.ledger == nil {
+		return nil, fmt.Errorf("no trade ledger configured")
+	}
+
+	if _, err := b.getPortfolio(ctx); err != nil {
+		return nil, err
+	}
+
+	return b.calculatePerformance(nil)
+}
+
+// performanceReport computes performance for the execution result, logging rather than failing the run
+func (b *DCABot) performanceReport(decisions []types.InvestmentDecision) *types.PerformanceReport {
+	if b.ledger == nil {
+		return nil
+	}
+
+	report, err := b.calculatePerformance(decisions)
+	if err != nil {
+		b.logger.Warn("Failed to compute performance report", "error", err)
+		return nil
+	}
+	if report == nil {
+		return nil
+	}
+
+	b.logger.Info("Performance",
+		"total_invested", report.Overall.TotalInvested.StringFixed(2),
+		"current_value", report.Overall.CurrentValue.StringFixed(2),
+		"unrealized_pnl", report.Overall.UnrealizedPnL.StringFixed(2),
+		"unrealized_pnl_percent", report.Overall.UnrealizedPnLPercent.String())
+
+	return report
+}
+
+// calculatePerformance prices the ledger using the portfolio and the latest decision prices
+func (b *DCABot) calculatePerformance(decisions []types.InvestmentDecision) (*types.PerformanceReport, error) {
+	entries, err := b.ledger.Entries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	if b.portfolio != nil {
+		for symbol, asset := range b.portfolio.Assets {
+			if asset.Status == services.ValuationOK {
+				prices[symbol] = asset.Price
+			}
+		}
+	}
+	for _, decision := range decisions {
+		prices[decision.Asset] = decision.Price
+	}
+
+	return performance.Calculate(entries, prices, time.Now()), nil
+}
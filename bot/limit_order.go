@@ -0,0 +1,319 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/coinbase-samples/advanced-trade-sdk-go/model"
+	"github.com/shopspring/decimal"
+)
+
+// Execution modes
+const (
+	ExecutionModeMarket = "market" // Market orders, paying taker fees
+	ExecutionModeLimit  = "limit"  // Post-only limit orders at the best bid, paying maker fees
+)
+
+// executionLimitMarket marks an order that was partly bought at market after the limit order timed out
+const executionLimitMarket = "limit+market"
+
+// limitOrderPollInterval is how often a working limit order is checked for fills
+const limitOrderPollInterval = 5 * time.Second
+
+// minOrderAmount is the smallest amount in USDC worth placing an order for
+var minOrderAmount = decimal.NewFromInt(1)
+
+// ValidateExecutionMode checks the execution mode and its limit order settings
+func ValidateExecutionMode(config *types.BotConfig) error {
+	switch config.ExecutionMode {
+	case "", ExecutionModeMarket:
+		return nil
+	case ExecutionModeLimit:
+		if config.LimitOrderTimeout <= 0 {
+			return fmt.Errorf("limit order timeout must be positive")
+		}
+		if config.LimitMaxReprices < 0 {
+			return fmt.Errorf("limit order reprices cannot be negative")
+		}
+		if config.LimitPriceTicks < 0 {
+			return fmt.Errorf("limit price ticks cannot be negative")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported execution mode: %s (must be market or limit)", config.ExecutionMode)
+	}
+}
+
+// increments are the price and size steps and the minimum order amount of a product
+type increments struct {
+	price     decimal.Decimal
+	size      decimal.Decimal
+	minAmount decimal.Decimal
+}
+
+// productIncrements fetches a product and parses the increments orders must be rounded to
+func (b *DCABot) productIncrements(ctx context.Context, productID string) (*increments, error) {
+	product, err := b.coinbaseService.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	priceIncrement, err := decimal.NewFromString(product.QuoteIncrement)
+	if err != nil || !priceIncrement.IsPositive() {
+		return nil, fmt.Errorf("invalid price increment %q for %s", product.QuoteIncrement, productID)
+	}
+	sizeIncrement, err := decimal.NewFromString(product.BaseIncrement)
+	if err != nil || !sizeIncrement.IsPositive() {
+		return nil, fmt.Errorf("invalid size increment %q for %s", product.BaseIncrement, productID)
+	}
+	minAmount, _ := decimal.NewFromString(product.QuoteMinSize)
+
+	return &increments{price: priceIncrement, size: sizeIncrement, minAmount: minAmount}, nil
+}
+
+// limitFill accumulates the fills of the limit orders placed for one decision
+type limitFill struct {
+	size  decimal.Decimal
+	value decimal.Decimal
+	fees  decimal.Decimal
+}
+
+// add records the fill of a finished order
+func (f *limitFill) add(order *model.Order) {
+	size, err := decimal.NewFromString(order.FilledSize)
+	if err != nil || size.IsZero() {
+		return
+	}
+	price, err := decimal.NewFromString(order.AverageFilledPrice)
+	if err != nil {
+		return
+	}
+	f.size = f.size.Add(size)
+	f.value = f.value.Add(size.Mul(price))
+	if fees, err := decimal.NewFromString(order.TotalFees); err == nil {
+		f.fees = f.fees.Add(fees)
+	}
+}
+
+// executeLimitBuy buys with post-only limit orders at or just inside the best bid. An order that is
+// not filled within the timeout is cancelled and the remainder repriced, up to the configured number
+// of reprices. Whatever is still unfilled afterwards is optionally bought at market.
+func (b *DCABot) executeLimitBuy(ctx context.Context, decision types.InvestmentDecision, result *types.OrderResult) error {
+	productID := decision.Asset + "-USDC"
+	logger := b.logger.With("asset", decision.Asset)
+	result.Execution = ExecutionModeLimit
+
+	increments, err := b.productIncrements(ctx, productID)
+	if err != nil {
+		return err
+	}
+	priceIncrement, sizeIncrement := increments.price, increments.size
+
+	fill := &limitFill{}
+	remaining := decision.Amount
+	var lastErr error
+
+	for attempt := 0; attempt <= b.config.LimitMaxReprices && remaining.GreaterThanOrEqual(minOrderAmount); attempt++ {
+		if err := checkDeadline(ctx); err != nil {
+			lastErr = err
+			break
+		}
+
+		bid, ask, err := b.bestBidAsk(ctx, decision.Asset)
+		if err != nil {
+			lastErr = err
+			break
+		}
+		price := makerPrice(bid, ask, priceIncrement, b.config.LimitPriceTicks)
+		size := remaining.Div(price).Div(sizeIncrement).Floor().Mul(sizeIncrement)
+		if !size.IsPositive() {
+			break
+		}
+
+		logger.Info("Placing post-only limit buy order",
+			"amount", remaining.StringFixed(2),
+			"size", size.String(),
+			"price", price.String(),
+			"attempt", attempt+1)
+
+		resp, err := b.coinbaseService.PlaceOrder(ctx, productID, "BUY", "post_only", size.String(), price.String())
+		if err != nil {
+			lastErr = err
+			break
+		}
+		if !resp.Success {
+			// Post-only orders are rejected when the book moved through the price; try again with a fresh price
+			logger.Warn("Limit order rejected", "reason", resp.FailureReason)
+			lastErr = fmt.Errorf("limit order rejected: %s", resp.FailureReason)
+			continue
+		}
+		result.OrderID = resp.OrderId
+
+		order, err := b.awaitLimitOrder(ctx, resp.OrderId)
+		if err != nil {
+			// The order may still be working, so nothing else may be bought against the same funds
+			return fmt.Errorf("failed to settle limit order %s: %w", resp.OrderId, err)
+		}
+
+		before := fill.value.Add(fill.fees)
+		fill.add(order)
+		b.recordOrder(decision.Asset, order, ledgerTag(decision))
+		remaining = remaining.Sub(fill.value.Add(fill.fees).Sub(before))
+
+		logger.Info("Limit order finished",
+			"order_id", order.OrderId,
+			"status", order.Status,
+			"filled_size", order.FilledSize,
+			"average_price", order.AverageFilledPrice)
+
+		if order.Status == services.OrderStatusFilled {
+			break
+		}
+	}
+
+	result.FilledSize = fill.size
+	result.Fee = fill.fees
+	result.Amount = fill.value.Add(fill.fees)
+	if fill.size.IsPositive() {
+		result.AveragePrice = fill.value.Div(fill.size)
+	}
+	result.FeeSavings = b.feeSavings(ctx, fill)
+
+	if remaining.GreaterThanOrEqual(minOrderAmount) && b.config.LimitMarketFallback {
+		if err := checkDeadline(ctx); err != nil {
+			return err
+		}
+
+		fallback := decision
+		fallback.Amount = remaining
+		logger.Info("Limit order not filled, buying remainder at market", "amount", remaining.StringFixed(2))
+
+		marketFill, err := b.placeMarketBuy(ctx, fallback)
+		if err != nil {
+			return err
+		}
+		result.OrderID = marketFill.OrderID
+		result.Execution = executionLimitMarket
+		result.Amount = result.Amount.Add(marketFill.QuoteAmount)
+		result.FilledSize = fill.size.Add(marketFill.Quantity)
+		result.Fee = fill.fees.Add(marketFill.Fee)
+		if result.FilledSize.IsPositive() {
+			result.AveragePrice = fill.value.Add(marketFill.QuoteAmount).Div(result.FilledSize)
+		}
+		return nil
+	}
+
+	if !fill.size.IsPositive() {
+		if lastErr != nil {
+			return lastErr
+		}
+		return fmt.Errorf("limit order not filled after %d attempts", b.config.LimitMaxReprices+1)
+	}
+
+	return nil
+}
+
+// awaitLimitOrder polls an order until it is done or the timeout passes, then cancels what is left.
+// It returns the final state of the order including any fills that raced with the cancel.
+func (b *DCABot) awaitLimitOrder(ctx context.Context, orderID string) (*model.Order, error) {
+	wait := b.config.LimitOrderTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline) - minOrderTimeRemaining; remaining < wait {
+			wait = remaining
+		}
+	}
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	poll := time.NewTicker(limitOrderPollInterval)
+	defer poll.Stop()
+
+waiting:
+	for {
+		select {
+		case <-ctx.Done():
+			break waiting
+		case <-timeout.C:
+			break waiting
+		case <-poll.C:
+			order, err := b.coinbaseService.GetOrder(ctx, orderID)
+			if err != nil {
+				b.logger.Warn("Failed to check limit order", "order_id", orderID, "error", err)
+				continue
+			}
+			if orderDone(order.Status) {
+				return order, nil
+			}
+		}
+	}
+
+	// Cancel even if the run was cancelled, so no order is left working on the book
+	ctx = context.WithoutCancel(ctx)
+	if err := b.coinbaseService.CancelOrder(ctx, orderID); err != nil {
+		// The order may have filled since the last poll, which makes the cancel fail
+		order, getErr := b.coinbaseService.GetOrder(ctx, orderID)
+		if getErr == nil && orderDone(order.Status) {
+			return order, nil
+		}
+		return nil, err
+	}
+	return b.coinbaseService.GetOrder(ctx, orderID)
+}
+
+// orderDone reports whether an order status is final
+func orderDone(status string) bool {
+	switch status {
+	case services.OrderStatusFilled, services.OrderStatusCancelled, services.OrderStatusExpired, services.OrderStatusFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// bestBidAsk returns the top of book from the live price feed, or from the REST order book
+func (b *DCABot) bestBidAsk(ctx context.Context, asset string) (decimal.Decimal, decimal.Decimal, error) {
+	if quote, ok := b.liveQuote(asset); ok && quote.BestBid.IsPositive() && quote.BestAsk.IsPositive() {
+		return quote.BestBid, quote.BestAsk, nil
+	}
+
+	bid, ask, err := b.coinbaseService.GetBestBidAsk(ctx, asset+"-USDC")
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	if !bid.IsPositive() {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("no bids in order book for %s", asset)
+	}
+	return bid, ask, nil
+}
+
+// makerPrice returns the limit price the given number of increments above the best bid,
+// kept below the best ask so the order rests on the book
+func makerPrice(bid, ask, increment decimal.Decimal, ticks int) decimal.Decimal {
+	price := bid.Add(increment.Mul(decimal.NewFromInt(int64(ticks))))
+	if ask.IsPositive() && price.GreaterThanOrEqual(ask) {
+		price = ask.Sub(increment)
+	}
+	if price.LessThan(bid) {
+		price = bid
+	}
+	return price.Div(increment).Floor().Mul(increment)
+}
+
+// feeSavings returns the taker fees a market order for the filled value would have paid, less the
+// maker fees actually paid. Returns zero if the fee rates are unavailable.
+func (b *DCABot) feeSavings(ctx context.Context, fill *limitFill) decimal.Decimal {
+	if !fill.value.IsPositive() {
+		return decimal.Zero
+	}
+
+	_, takerRate, err := b.coinbaseService.GetFeeRates(ctx)
+	if err != nil {
+		b.logger.Warn("Failed to get fee rates, fee savings not tracked", "error", err)
+		return decimal.Zero
+	}
+
+	return fill.value.Mul(takerRate).Sub(fill.fees)
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/coinbase-samples/advanced-trade-sdk-go/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestMakerPrice(t *testing.T) {
+	tests := []struct {
+		bid, ask string
+		ticks    int
+		want     string
+	}{
+		{"100", "101", 0, "100"},
+		{"100", "101", 1, "100.01"},
+		{"100", "100.05", 10, "100.04"}, // Kept below the ask
+		{"100", "100.01", 1, "100"},     // One tick spread stays at the bid
+		{"100", "100", 1, "100"},        // Locked book stays at the bid
+		{"100", "0", 3, "100.03"},       // No asks
+		{"100.005", "101", 0, "100"},    // Rounded down to the increment
+	}
+
+	increment := decimal.RequireFromString("0.01")
+	for _, tt := range tests {
+		got := makerPrice(decimal.RequireFromString(tt.bid), decimal.RequireFromString(tt.ask), increment, tt.ticks)
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("makerPrice(%s, %s, %d ticks) = %s, want %s", tt.bid, tt.ask, tt.ticks, got, tt.want)
+		}
+	}
+}
+
+func TestLimitFillAdd(t *testing.T) {
+	var fill limitFill
+	fill.add(&model.Order{FilledSize: "0.5", AverageFilledPrice: "100", TotalFees: "0.1"})
+	fill.add(&model.Order{FilledSize: "0", AverageFilledPrice: "0"}) // Cancelled without fills
+	fill.add(&model.Order{FilledSize: "0.25", AverageFilledPrice: "102", TotalFees: "0.05"})
+
+	if !fill.size.Equal(decimal.RequireFromString("0.75")) ||
+		!fill.value.Equal(decimal.RequireFromString("75.5")) ||
+		!fill.fees.Equal(decimal.RequireFromString("0.15")) {
+		t.Errorf("fill = size %s value %s fees %s, want 0.75 75.5 0.15", fill.size, fill.value, fill.fees)
+	}
+}
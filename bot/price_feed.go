@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"fmt"
+
+	"moonshot/notifier"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceFeed provides live quotes, e.g. from the WebSocket market data service
+type PriceFeed interface {
+	Quote(productID string) (types.Quote, bool)
+}
+
+// SetPriceFeed makes the strategy and pre-trade checks use live quotes from feed
+func (b *DCABot) SetPriceFeed(feed PriceFeed) {
+	b.priceFeed = feed
+}
+
+// liveQuote returns the live quote for an asset, if a price feed is set and has a recent quote
+func (b *DCABot) liveQuote(symbol string) (types.Quote, bool) {
+	if b.priceFeed == nil {
+		return types.Quote{}, false
+	}
+	quote, ok := b.priceFeed.Quote(symbol + "-USDC")
+	if !ok || !quote.LastPrice.IsPositive() {
+		return types.Quote{}, false
+	}
+	return quote, true
+}
+
+// applyLivePrices replaces snapshot prices with live last trade prices where available
+func (b *DCABot) applyLivePrices(snapshot *types.MarketSnapshot) {
+	for symbol := range snapshot.Prices {
+		if quote, ok := b.liveQuote(symbol); ok {
+			snapshot.Prices[symbol] = quote.LastPrice
+		}
+	}
+}
+
+// checkPriceDeviation blocks an order when the live ask has moved more than the configured
+// percentage away from the price the decision was made at
+func (b *DCABot) checkPriceDeviation(decision types.InvestmentDecision) error {
+	maxDeviation := b.config.MaxPriceDeviation
+	if !maxDeviation.IsPositive() || !decision.Price.IsPositive() {
+		return nil
+	}
+
+	quote, ok := b.liveQuote(decision.Asset)
+	if !ok || !quote.BestAsk.IsPositive() {
+		return nil
+	}
+
+	deviation := quote.BestAsk.Sub(decision.Price).Abs().Div(decision.Price).Mul(decimal.NewFromInt(100))
+	if deviation.LessThanOrEqual(maxDeviation) {
+		return nil
+	}
+
+	b.notify(notifier.Event{
+		Type:     notifier.EventRiskBlocked,
+		Decision: &decision,
+		Reason: fmt.Sprintf("Order blocked: %s ask %s is %s%% away from the decision price %s",
+			decision.Asset, quote.BestAsk.StringFixed(2), deviation.StringFixed(2), decision.Price.StringFixed(2)),
+	})
+	return fmt.Errorf("live ask %s deviates %s%% from decision price %s (max %s%%)",
+		quote.BestAsk.String(), deviation.StringFixed(2), decision.Price.String(), maxDeviation.String())
+}
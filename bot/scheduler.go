@@ -0,0 +1,325 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"moonshot/types"
+)
+
+// Supported investment frequencies
+const (
+	FrequencyDaily    = "daily"
+	FrequencyWeekly   = "weekly"
+	FrequencyBiweekly = "biweekly"
+	FrequencyMonthly  = "monthly"
+)
+
+// maxSchedulerSleep caps how long the scheduler sleeps at once so that
+// wall clock jumps (e.g. host suspend/resume) are picked up quickly
+const maxSchedulerSleep = time.Minute
+
+// biweeklyAnchor is a Monday used to decide which weeks are "on" weeks for biweekly schedules
+var biweeklyAnchor = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// Schedule computes execution times from the bot configuration
+type Schedule struct {
+	Frequency  string
+	Weekday    time.Weekday
+	DayOfMonth int
+	Hour       int
+	Minute     int
+	Location   *time.Location
+}
+
+// NewSchedule builds a schedule from the bot configuration
+func NewSchedule(config *types.BotConfig) (*Schedule, error) {
+	frequency := strings.ToLower(config.InvestmentFrequency)
+	switch frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyBiweekly, FrequencyMonthly:
+	default:
+		return nil, fmt.Errorf("unsupported investment frequency: %s", config.InvestmentFrequency)
+	}
+
+	executionTime, err := time.Parse("15:04", config.ExecutionTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid execution time %q, expected HH:MM: %w", config.ExecutionTime, err)
+	}
+
+	weekday, err := parseWeekday(config.ExecutionWeekday)
+	if err != nil {
+		return nil, err
+	}
+
+	dayOfMonth := config.ExecutionDayOfMonth
+	if dayOfMonth == 0 {
+		dayOfMonth = 1
+	}
+	if dayOfMonth < 1 || dayOfMonth > 31 {
+		return nil, fmt.Errorf("execution day of month must be between 1 and 31, got %d", dayOfMonth)
+	}
+
+	timezone := config.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+
+	return &Schedule{
+		Frequency:  frequency,
+		Weekday:    weekday,
+		DayOfMonth: dayOfMonth,
+		Hour:       executionTime.Hour(),
+		Minute:     executionTime.Minute(),
+		Location:   location,
+	}, nil
+}
+
+// Next returns the first scheduled execution strictly after the given time
+func (s *Schedule) Next(after time.Time) time.Time {
+	local := after.In(s.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location)
+
+	// Monthly schedules can be up to ~31 days apart; biweekly up to 14
+	for i := 0; i <= 62; i++ {
+		candidate := s.at(day.AddDate(0, 0, i))
+		if s.matches(candidate) && candidate.After(after) {
+			return candidate
+		}
+	}
+
+	return time.Time{}
+}
+
+// Prev returns the most recent scheduled execution at or before the given time
+func (s *Schedule) Prev(at time.Time) time.Time {
+	local := at.In(s.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location)
+
+	for i := 0; i <= 62; i++ {
+		candidate := s.at(day.AddDate(0, 0, -i))
+		if s.matches(candidate) && !candidate.After(at) {
+			return candidate
+		}
+	}
+
+	return time.Time{}
+}
+
+// String returns a human readable description of the schedule
+func (s *Schedule) String() string {
+	clock := fmt.Sprintf("%02d:%02d %s", s.Hour, s.Minute, s.Location)
+	switch s.Frequency {
+	case FrequencyWeekly:
+		return fmt.Sprintf("weekly on %s at %s", s.Weekday, clock)
+	case FrequencyBiweekly:
+		return fmt.Sprintf("every other %s at %s", s.Weekday, clock)
+	case FrequencyMonthly:
+		return fmt.Sprintf("monthly on day %d at %s", s.DayOfMonth, clock)
+	default:
+		return fmt.Sprintf("daily at %s", clock)
+	}
+}
+
+// at returns the execution time on the given day
+func (s *Schedule) at(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+}
+
+// matches reports whether the given day is an execution day
+func (s *Schedule) matches(t time.Time) bool {
+	switch s.Frequency {
+	case FrequencyWeekly:
+		return t.Weekday() == s.Weekday
+
+	case FrequencyBiweekly:
+		if t.Weekday() != s.Weekday {
+			return false
+		}
+		days := int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Sub(biweeklyAnchor).Hours() / 24)
+		return (days/7)%2 == 0
+
+	case FrequencyMonthly:
+		// Clamp to the last day for short months (e.g. day 31 in February)
+		lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, s.Location).Day()
+		day := s.DayOfMonth
+		if day > lastDay {
+			day = lastDay
+		}
+		return t.Day() == day
+
+	default: // Daily
+		return true
+	}
+}
+
+// parseWeekday parses a weekday name, defaulting to Monday
+func parseWeekday(name string) (time.Weekday, error) {
+	if name == "" {
+		return time.Monday, nil
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+			return d, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("invalid execution weekday: %s", name)
+}
+
+// Scheduler tasks
+const (
+	taskRun      = "run"
+	taskDipCheck = "dip_check"
+	taskTWAP     = "twap"
+)
+
+// Scheduler runs the DCA bot on its configured schedule as a long-lived process
+type Scheduler struct {
+	bot      *DCABot
+	schedule *Schedule
+}
+
+// NewScheduler creates a new scheduler instance
+func NewScheduler(bot *DCABot) *Scheduler {
+	return &Scheduler{
+		bot:      bot,
+		schedule: bot.GetSchedule(),
+	}
+}
+
+// Run blocks, executing the bot at each scheduled time until the context is cancelled. Between
+// runs it checks for dips and executes TWAP child orders as they come due.
+// A run that is already in progress is allowed to finish before Run returns.
+func (s *Scheduler) Run(ctx context.Context) error {
+	state, err := s.bot.GetState()
+	if err != nil {
+		return fmt.Errorf("failed to load bot state: %w", err)
+	}
+
+	if !state.LastRunAt.IsZero() {
+		slog.Info("Last run", "at", state.LastRunAt.Format(time.RFC3339), "success", state.LastRunSuccess)
+	}
+	slog.Info("Scheduler started", "schedule", s.schedule.String())
+
+	var nextDip, twapRetryAt time.Time
+	interval := s.bot.DipCheckInterval()
+	if interval > 0 {
+		nextDip = time.Now().Add(interval)
+	}
+
+	for {
+		next := s.schedule.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("unable to compute next execution time")
+		}
+		slog.Info("Next execution scheduled", "at", next.Format(time.RFC3339))
+
+		for {
+			// Wake for whichever comes first: a TWAP child order, a dip check or the next run
+			at, task := next, taskRun
+			if !nextDip.IsZero() && nextDip.Before(at) {
+				at, task = nextDip, taskDipCheck
+			}
+			if due := s.bot.NextTWAPAt(); !due.IsZero() {
+				if due.Before(twapRetryAt) {
+					due = twapRetryAt
+				}
+				if due.Before(at) {
+					at, task = due, taskTWAP
+				}
+			}
+
+			if !s.waitUntil(ctx, at) {
+				slog.Info("Scheduler stopped")
+				return nil
+			}
+
+			// Detach from cancellation so a shutdown signal does not abort an in-flight run
+			runCtx := context.WithoutCancel(ctx)
+			switch task {
+			case taskTWAP:
+				if !s.continueTWAP(runCtx) {
+					twapRetryAt = time.Now().Add(maxSchedulerSleep)
+				}
+				continue
+			case taskDipCheck:
+				s.checkDips(runCtx)
+				nextDip = time.Now().Add(interval)
+				continue
+			}
+
+			s.runOnce(runCtx, next)
+			break
+		}
+	}
+}
+
+// runOnce executes the bot a single time; the bot persists the outcome to its state store
+func (s *Scheduler) runOnce(ctx context.Context, scheduledAt time.Time) {
+	slog.Info("Scheduled execution triggered", "scheduled_at", scheduledAt.Format(time.RFC3339))
+
+	result, err := s.bot.Execute(ctx)
+	if err != nil {
+		slog.Error("Bot execution failed", "error", err)
+		return
+	}
+
+	slog.Info("Scheduled execution completed",
+		"run_id", result.RunID, "success", result.Success, "total_invested", result.TotalInvested.String())
+}
+
+// checkDips evaluates the dip triggers a single time
+func (s *Scheduler) checkDips(ctx context.Context) {
+	result, err := s.bot.CheckDips(ctx)
+	if err != nil {
+		slog.Error("Dip check failed", "error", err)
+		return
+	}
+
+	if len(result.Orders) > 0 {
+		slog.Info("Dip buy executed",
+			"run_id", result.RunID, "success", result.Success, "total_invested", result.TotalInvested.String())
+	}
+}
+
+// continueTWAP executes the TWAP child orders that are due, returning false if the attempt failed
+func (s *Scheduler) continueTWAP(ctx context.Context) bool {
+	result, err := s.bot.ContinueTWAP(ctx)
+	if err != nil {
+		slog.Error("TWAP continuation failed", "error", err)
+		return false
+	}
+
+	slog.Info("TWAP child orders executed",
+		"run_id", result.RunID, "success", result.Success, "total_invested", result.TotalInvested.String())
+	return true
+}
+
+// waitUntil sleeps until the target time, returning false if the context was cancelled first
+func (s *Scheduler) waitUntil(ctx context.Context, target time.Time) bool {
+	for {
+		remaining := time.Until(target)
+		if remaining <= 0 {
+			return true
+		}
+		if remaining > maxSchedulerSleep {
+			remaining = maxSchedulerSleep
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
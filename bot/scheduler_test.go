@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"moonshot/types"
+)
+
+func TestScheduleNextPrev(t *testing.T) {
+	march := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		config     types.BotConfig
+		at         time.Time
+		next, prev time.Time
+	}{
+		{
+			name:   "daily before the execution time",
+			config: types.BotConfig{InvestmentFrequency: "daily", ExecutionTime: "09:30"},
+			at:     march(4, 9, 29),
+			next:   march(4, 9, 30),
+			prev:   march(3, 9, 30),
+		},
+		{
+			name:   "daily at the execution time",
+			config: types.BotConfig{InvestmentFrequency: "daily", ExecutionTime: "09:30"},
+			at:     march(4, 9, 30),
+			next:   march(5, 9, 30),
+			prev:   march(4, 9, 30),
+		},
+		{
+			name:   "weekly with a short weekday name",
+			config: types.BotConfig{InvestmentFrequency: "Weekly", ExecutionTime: "08:00", ExecutionWeekday: "Fri"},
+			at:     march(4, 0, 0),
+			next:   march(8, 8, 0),
+			prev:   time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "biweekly skips the off week",
+			config: types.BotConfig{InvestmentFrequency: "biweekly", ExecutionTime: "12:00", ExecutionWeekday: "monday"},
+			at:     march(5, 0, 0),
+			next:   march(18, 12, 0),
+			prev:   march(4, 12, 0),
+		},
+		{
+			name:   "monthly",
+			config: types.BotConfig{InvestmentFrequency: "monthly", ExecutionTime: "00:00", ExecutionDayOfMonth: 15},
+			at:     march(20, 0, 0),
+			next:   time.Date(2024, time.April, 15, 0, 0, 0, 0, time.UTC),
+			prev:   march(15, 0, 0),
+		},
+		{
+			name:   "monthly on the 31st in February",
+			config: types.BotConfig{InvestmentFrequency: "monthly", ExecutionTime: "00:00", ExecutionDayOfMonth: 31},
+			at:     time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+			prev:   time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "monthly on the 31st after a short month",
+			config: types.BotConfig{InvestmentFrequency: "monthly", ExecutionTime: "00:00", ExecutionDayOfMonth: 31},
+			at:     time.Date(2024, time.April, 30, 12, 0, 0, 0, time.UTC),
+			next:   time.Date(2024, time.May, 31, 0, 0, 0, 0, time.UTC),
+			prev:   time.Date(2024, time.April, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := NewSchedule(&tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(tt.at); !got.Equal(tt.next) {
+				t.Errorf("Next(%s) = %s, want %s", tt.at, got, tt.next)
+			}
+			if got := schedule.Prev(tt.at); !got.Equal(tt.prev) {
+				t.Errorf("Prev(%s) = %s, want %s", tt.at, got, tt.prev)
+			}
+		})
+	}
+}
+
+func TestScheduleTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	schedule, err := NewSchedule(&types.BotConfig{InvestmentFrequency: "daily", ExecutionTime: "09:00", Timezone: "America/New_York"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks move forward at 2am on March 10, so the run keeps its local time but moves an hour in UTC
+	before := time.Date(2024, time.March, 9, 14, 0, 0, 0, time.UTC)
+	first := schedule.Next(before)
+	second := schedule.Next(first)
+	if want := time.Date(2024, time.March, 10, 9, 0, 0, 0, newYork); !first.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", before, first, want)
+	}
+	if gap := second.Sub(first); gap != 24*time.Hour {
+		t.Errorf("runs after the DST change are %s apart, want 24h", gap)
+	}
+	if got := first.UTC().Hour(); got != 13 {
+		t.Errorf("run after the DST change at %d:00 UTC, want 13:00", got)
+	}
+}
+
+func TestNewScheduleErrors(t *testing.T) {
+	configs := map[string]types.BotConfig{
+		"unsupported frequency":     {InvestmentFrequency: "hourly", ExecutionTime: "09:00"},
+		"invalid execution time":    {InvestmentFrequency: "daily", ExecutionTime: "9am"},
+		"invalid weekday":           {InvestmentFrequency: "weekly", ExecutionTime: "09:00", ExecutionWeekday: "someday"},
+		"day of month out of range": {InvestmentFrequency: "monthly", ExecutionTime: "09:00", ExecutionDayOfMonth: 32},
+		"invalid time zone":         {InvestmentFrequency: "daily", ExecutionTime: "09:00", Timezone: "Mars/Olympus"},
+	}
+
+	for name, config := range configs {
+		if _, err := NewSchedule(&config); err == nil {
+			t.Errorf("%s: NewSchedule succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"moonshot/indicators"
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Signals combined into the investment multiplier
+const (
+	SignalFNG   = "fng"
+	SignalRSI   = "rsi"
+	SignalMayer = "mayer"
+	SignalATH   = "ath"
+)
+
+// signalRange maps an indicator onto the multiplier range: the value at which the maximum multiplier
+// applies, the value at which the minimum applies, and linear in between
+type signalRange struct {
+	indicator string
+	cheap     decimal.Decimal
+	expensive decimal.Decimal
+}
+
+// priceSignals are the price-based signals and the indicator ranges they scale over
+var priceSignals = map[string]signalRange{
+	SignalRSI:   {indicators.RSI14, decimal.NewFromInt(30), decimal.NewFromInt(70)},
+	SignalMayer: {indicators.MayerMultiple, decimal.NewFromFloat(0.8), decimal.NewFromFloat(2.4)},
+	SignalATH:   {indicators.ATHDistancePercent, decimal.NewFromInt(70), decimal.Zero},
+}
+
+// ValidateSignalWeights checks the signal weights and indicator history
+func ValidateSignalWeights(config *types.BotConfig) error {
+	weights := map[string]decimal.Decimal{
+		SignalFNG:   config.FNGWeight,
+		SignalRSI:   config.RSIWeight,
+		SignalMayer: config.MayerWeight,
+		SignalATH:   config.ATHWeight,
+	}
+	total := decimal.Zero
+	for signal, weight := range weights {
+		if weight.IsNegative() {
+			return fmt.Errorf("%s weight cannot be negative", signal)
+		}
+		total = total.Add(weight)
+	}
+	if !total.IsPositive() {
+		return fmt.Errorf("at least one signal weight must be positive")
+	}
+	if usesPriceSignals(config) && config.IndicatorHistory <= 0 {
+		return fmt.Errorf("indicator history must be positive")
+	}
+	return nil
+}
+
+// CandleSource provides price history, e.g. the candle history backed by a local store
+type CandleSource interface {
+	Candles(ctx context.Context, productID, granularity string, start, end time.Time) ([]types.Candle, error)
+}
+
+// SetCandleSource makes the indicators read price history from source instead of fetching it all from Coinbase
+func (b *DCABot) SetCandleSource(source CandleSource) {
+	b.candleSource = source
+}
+
+// candles returns the price history of a product from the candle source, or from Coinbase
+func (b *DCABot) candles(ctx context.Context, productID, granularity string, start, end time.Time) ([]types.Candle, error) {
+	if b.candleSource != nil {
+		return b.candleSource.Candles(ctx, productID, granularity, start, end)
+	}
+	return b.coinbaseService.GetCandleHistory(ctx, productID, start, end, granularity)
+}
+
+// usesPriceSignals reports whether any price-based signal has a weight
+func usesPriceSignals(config *types.BotConfig) bool {
+	return config.RSIWeight.IsPositive() || config.MayerWeight.IsPositive() || config.ATHWeight.IsPositive()
+}
+
+// usesIndicators reports whether the strategy needs indicators computed from price history
+func usesIndicators(config *types.BotConfig) bool {
+	return usesPriceSignals(config) || usesVolatilitySizing(config)
+}
+
+// indicatorHistory returns how much daily price history the configured indicators need
+func (b *DCABot) indicatorHistory() time.Duration {
+	history := time.Duration(0)
+	if usesPriceSignals(b.config) {
+		history = b.config.IndicatorHistory
+	}
+	if usesVolatilitySizing(b.config) {
+		// One extra day for the first return and one for the candle still in progress
+		volatilityHistory := time.Duration(b.config.VolatilityLookback+2) * 24 * time.Hour
+		if volatilityHistory > history {
+			history = volatilityHistory
+		}
+	}
+	return history
+}
+
+// signalWeights returns the weight of each signal
+func (b *DCABot) signalWeights() map[string]decimal.Decimal {
+	return map[string]decimal.Decimal{
+		SignalFNG:   b.config.FNGWeight,
+		SignalRSI:   b.config.RSIWeight,
+		SignalMayer: b.config.MayerWeight,
+		SignalATH:   b.config.ATHWeight,
+	}
+}
+
+// addIndicators computes the technical indicators of every priced asset from its daily candles. Only
+// done when a price-based signal is weighted; an asset whose candles can't be fetched gets none and
+// falls back to the remaining signals.
+func (b *DCABot) addIndicators(ctx context.Context, snapshot *types.MarketSnapshot) {
+	if !usesIndicators(b.config) {
+		return
+	}
+
+	snapshot.Indicators = make(map[string]map[string]decimal.Decimal)
+	for _, asset := range b.configuredAssets() {
+		price, ok := snapshot.Prices[asset]
+		if !ok {
+			continue
+		}
+
+		candles, err := b.candles(ctx, asset+"-USDC", services.GranularityOneDay,
+			snapshot.Timestamp.Add(-b.indicatorHistory()), snapshot.Timestamp)
+		if err != nil {
+			b.logger.Warn("Failed to get candles for indicators", "asset", asset, "error", err)
+			continue
+		}
+
+		values := indicators.Compute(price, candles)
+		if usesVolatilitySizing(b.config) {
+			if volatility, err := indicators.Volatility(indicators.Closes(candles), b.config.VolatilityLookback); err == nil {
+				values[indicators.RealizedVolatility] = volatility
+			}
+		}
+		snapshot.Indicators[asset] = values
+
+		args := []any{"asset", asset, "candles", len(candles)}
+		for _, name := range sortedKeys(values) {
+			args = append(args, name, values[name].StringFixed(2))
+		}
+		b.logger.Info("Computed indicators", args...)
+	}
+}
+
+// assetMultiplier returns the weighted average of the multipliers of the signals available for an
+// asset, along with each signal's multiplier. Signals without a weight or without enough data are
+// left out; with none left the Fear & Greed multiplier is used as is.
+func (b *DCABot) assetMultiplier(snapshot *types.MarketSnapshot, asset string) (decimal.Decimal, map[string]decimal.Decimal) {
+	fngMultiplier := snapshot.FNGIndex.Multiplier
+	weights := b.signalWeights()
+
+	signals := make(map[string]decimal.Decimal)
+	if weights[SignalFNG].IsPositive() {
+		signals[SignalFNG] = fngMultiplier
+	}
+	values := snapshot.Indicators[asset]
+	for signal, r := range priceSignals {
+		value, ok := values[r.indicator]
+		if !ok || !weights[signal].IsPositive() {
+			continue
+		}
+		signals[signal] = b.scaleSignal(value, r)
+	}
+
+	total, weighted := decimal.Zero, decimal.Zero
+	for signal, multiplier := range signals {
+		total = total.Add(weights[signal])
+		weighted = weighted.Add(multiplier.Mul(weights[signal]))
+	}
+	if !total.IsPositive() {
+		return fngMultiplier, map[string]decimal.Decimal{SignalFNG: fngMultiplier}
+	}
+
+	return weighted.Div(total).Round(4), signals
+}
+
+// scaleSignal maps an indicator value linearly onto the configured multiplier range
+func (b *DCABot) scaleSignal(value decimal.Decimal, r signalRange) decimal.Decimal {
+	score := r.expensive.Sub(value).Div(r.expensive.Sub(r.cheap))
+	score = decimal.Min(decimal.Max(score, decimal.Zero), decimal.NewFromInt(1))
+
+	spread := b.config.MaxMultiplier.Sub(b.config.MinMultiplier)
+	return b.config.MinMultiplier.Add(spread.Mul(score)).Round(4)
+}
+
+// regularInvestment returns the base investment scaled by each asset's multiplier, before caps
+func (b *DCABot) regularInvestment(snapshot *types.MarketSnapshot) decimal.Decimal {
+	hundred := decimal.NewFromInt(100)
+	allocations := b.allocations()
+
+	total := decimal.Zero
+	for _, asset := range b.configuredAssets() {
+		multiplier, _, _ := b.sizedMultiplier(snapshot, asset)
+		total = total.Add(b.config.WeeklyBaseInvestment.Mul(multiplier).Mul(allocations[asset]).Div(hundred))
+	}
+	return total
+}
+
+// signalReason describes how a multiplier was derived from the signals
+func signalReason(multiplier decimal.Decimal, fngIndex *types.FearGreedIndex, signals, values map[string]decimal.Decimal) string {
+	parts := []string{fmt.Sprintf("F&G index %d", fngIndex.Value)}
+	if fngMultiplier, ok := signals[SignalFNG]; ok {
+		parts[0] += " → " + fngMultiplier.String()
+	}
+	for _, signal := range []string{SignalRSI, SignalMayer, SignalATH} {
+		signalMultiplier, ok := signals[signal]
+		if !ok {
+			continue
+		}
+		r := priceSignals[signal]
+		parts = append(parts, fmt.Sprintf("%s %s → %s", r.indicator, values[r.indicator].StringFixed(2), signalMultiplier.String()))
+	}
+	return fmt.Sprintf("DCA with multiplier %s (%s)", multiplier.String(), strings.Join(parts, ", "))
+}
+
+// sortedKeys returns the keys of a map in order, for stable log output
+func sortedKeys(m map[string]decimal.Decimal) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// marketSnapshot fetches the portfolio, with its order book prices, and the Fear & Greed Index
+// concurrently into a single point-in-time snapshot. Decisions reuse the valuation prices, or the
+// live last trade price when a price feed is set.
+func (b *DCABot) marketSnapshot(ctx context.Context) (*types.MarketSnapshot, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	snapshot := &types.MarketSnapshot{
+		Timestamp: time.Now(),
+		Prices:    make(map[string]decimal.Decimal),
+	}
+
+	// The first failure cancels the remaining requests and is the error reported
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		portfolio, err := b.getPortfolio(ctx)
+		if err != nil {
+			fail(err)
+			return
+		}
+		snapshot.Portfolio = portfolio
+	}()
+	go func() {
+		defer wg.Done()
+		fngIndex, err := b.fngService.GetFearGreedIndex(ctx)
+		if err != nil {
+			fail(fmt.Errorf("failed to get FNG index: %w", err))
+			return
+		}
+		snapshot.FNGIndex = fngIndex
+	}()
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	for symbol, asset := range snapshot.Portfolio.Assets {
+		if asset.Status == services.ValuationOK {
+			snapshot.Prices[symbol] = asset.Price
+		}
+	}
+	b.applyLivePrices(snapshot)
+
+	b.logger.Debug("Gathered market snapshot", "duration", time.Since(snapshot.Timestamp).String())
+
+	return snapshot, nil
+}
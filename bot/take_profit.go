@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"moonshot/notifier"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Take-profit conditions
+const (
+	TakeProfitAny = "any" // Sell when any enabled condition holds
+	TakeProfitAll = "all" // Sell only when every enabled condition holds
+)
+
+// ValidateTakeProfit checks the take-profit configuration
+func ValidateTakeProfit(config *types.BotConfig) error {
+	if !config.TakeProfitEnabled {
+		return nil
+	}
+	switch strings.ToLower(config.TakeProfitCondition) {
+	case "", TakeProfitAny, TakeProfitAll:
+	default:
+		return fmt.Errorf("unsupported take-profit condition: %s (must be any or all)", config.TakeProfitCondition)
+	}
+	if config.FNGSellThreshold < 0 || config.FNGSellThreshold > 100 {
+		return fmt.Errorf("FNG sell threshold must be between 0 and 100")
+	}
+	if config.TakeProfitPercentage.IsNegative() {
+		return fmt.Errorf("take-profit percentage cannot be negative")
+	}
+	if config.FNGSellThreshold == 0 && !config.TakeProfitPercentage.IsPositive() {
+		return fmt.Errorf("take-profit needs an FNG sell threshold or a take-profit percentage")
+	}
+	if !config.SellPercentage.IsPositive() || config.SellPercentage.GreaterThan(decimal.NewFromInt(100)) {
+		return fmt.Errorf("sell percentage must be between 0 and 100")
+	}
+	if config.MaxSellPerPeriod.IsNegative() {
+		return fmt.Errorf("max sell per period cannot be negative")
+	}
+	return nil
+}
+
+// takeProfitDecisions returns a sell decision for every asset meeting the take-profit conditions,
+// sized as the configured percentage of its available holdings within the period cap, along with
+// the average cost of each asset sold. Assets without a cost basis in the ledger, or trading at or
+// below it, are never sold.
+func (b *DCABot) takeProfitDecisions(snapshot *types.MarketSnapshot, state *types.BotState, now time.Time) ([]types.InvestmentDecision, map[string]decimal.Decimal) {
+	if period := b.schedule.Prev(now); !state.SellPeriod.Equal(period) {
+		state.SellPeriod = period
+		state.SoldInPeriod = decimal.Zero
+	}
+	remaining := b.config.MaxSellPerPeriod.Sub(state.SoldInPeriod)
+	if b.config.MaxSellPerPeriod.IsPositive() && !remaining.GreaterThanOrEqual(minOrderAmount) {
+		b.logger.Info("Take-profit skipped, max sell for this period reached")
+		return nil, nil
+	}
+
+	if b.ledger == nil {
+		b.logger.Warn("Take-profit skipped, no trade ledger configured for the cost basis")
+		return nil, nil
+	}
+	report, err := b.calculatePerformance(nil)
+	if err != nil || report == nil {
+		b.logger.Warn("Take-profit skipped, no cost basis available", "error", err)
+		return nil, nil
+	}
+
+	hundred := decimal.NewFromInt(100)
+	greedy := b.config.FNGSellThreshold > 0 && snapshot.FNGIndex.Value >= b.config.FNGSellThreshold
+
+	var decisions []types.InvestmentDecision
+	costs := make(map[string]decimal.Decimal)
+	for _, asset := range b.configuredAssets() {
+		price, ok := snapshot.Prices[asset]
+		holding := snapshot.Portfolio.Assets[asset]
+		perf := report.Assets[asset]
+		if !ok || holding == nil || perf == nil || !perf.Quantity.IsPositive() || !perf.CostBasis.IsPositive() {
+			continue
+		}
+
+		averageCost := perf.CostBasis.Div(perf.Quantity)
+		gain := price.Sub(averageCost).Div(averageCost).Mul(hundred)
+		if !gain.IsPositive() {
+			continue
+		}
+
+		var met, missed []string
+		if b.config.FNGSellThreshold > 0 {
+			condition := fmt.Sprintf("F&G %d vs sell threshold %d", snapshot.FNGIndex.Value, b.config.FNGSellThreshold)
+			if greedy {
+				met = append(met, condition)
+			} else {
+				missed = append(missed, condition)
+			}
+		}
+		if b.config.TakeProfitPercentage.IsPositive() {
+			condition := fmt.Sprintf("%s%% gain over average cost %s vs %s%% target",
+				gain.StringFixed(1), averageCost.StringFixed(2), b.config.TakeProfitPercentage.String())
+			if gain.GreaterThanOrEqual(b.config.TakeProfitPercentage) {
+				met = append(met, condition)
+			} else {
+				missed = append(missed, condition)
+			}
+		}
+		if len(met) == 0 || (strings.EqualFold(b.config.TakeProfitCondition, TakeProfitAll) && len(missed) > 0) {
+			continue
+		}
+
+		amount := holding.Available.Mul(b.config.SellPercentage).Div(hundred).Mul(price)
+		if b.config.MaxSellPerPeriod.IsPositive() {
+			amount = decimal.Min(amount, remaining)
+		}
+		amount = amount.RoundDown(2)
+		if amount.LessThan(minOrderAmount) {
+			continue
+		}
+		remaining = remaining.Sub(amount)
+
+		costs[asset] = averageCost
+		decisions = append(decisions, types.InvestmentDecision{
+			Asset:     asset,
+			Action:    "sell",
+			Amount:    amount,
+			Price:     price,
+			Reason:    "Take profit: " + strings.Join(met, ", "),
+			Tag:       LedgerTagTakeProfit,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return decisions, costs
+}
+
+// executeSellOrder sells the decision's amount at market and records the realized P&L against the
+// asset's average cost
+func (b *DCABot) executeSellOrder(ctx context.Context, decision types.InvestmentDecision, averageCost decimal.Decimal, result *types.OrderResult) error {
+	productID := decision.Asset + "-USDC"
+	logger := b.logger.With("asset", decision.Asset)
+
+	increments, err := b.productIncrements(ctx, productID)
+	if err != nil {
+		return err
+	}
+	size := decision.Amount.Div(decision.Price).Div(increments.size).Floor().Mul(increments.size)
+	if !size.IsPositive() {
+		return fmt.Errorf("sell amount %s is below the minimum size for %s", decision.Amount.String(), productID)
+	}
+
+	logger.Info("Placing market sell order",
+		"amount", decision.Amount.String(),
+		"size", size.String(),
+		"price", decision.Price.String())
+
+	resp, err := b.coinbaseService.PlaceOrder(ctx, productID, "SELL", "market", size.String(), "")
+	if err != nil {
+		return fmt.Errorf("failed to place order: %w", err)
+	}
+	if !resp.Success {
+		logger.Error("Order failed", "reason", resp.FailureReason)
+		return fmt.Errorf("order failed: %s", resp.FailureReason)
+	}
+	logger.Info("Order placed", "order_id", resp.OrderId)
+
+	decision.Amount = size.Mul(decision.Price)
+	fill := b.recordFill(ctx, decision, resp.OrderId, ledgerTag(decision))
+	proceeds := fill.QuoteAmount.Sub(fill.Fee)
+	pnl := proceeds.Sub(averageCost.Mul(fill.Quantity))
+
+	result.OrderID = fill.OrderID
+	result.Execution = ExecutionModeMarket
+	result.Amount = proceeds
+	result.FilledSize = fill.Quantity
+	result.AveragePrice = fill.Price
+	result.Fee = fill.Fee
+	result.RealizedPnL = &pnl
+	return nil
+}
+
+// executeSells places the take-profit sells, adding their proceeds and realized P&L to the result
+func (b *DCABot) executeSells(ctx context.Context, sells []types.InvestmentDecision, costs map[string]decimal.Decimal, state *types.BotState, result *types.ExecutionResult) {
+	for _, decision := range sells {
+		order := types.OrderResult{Asset: decision.Asset, Side: "SELL", Amount: decision.Amount}
+		err := checkDeadline(ctx)
+		if err == nil {
+			err = b.executeSellOrder(ctx, decision, costs[decision.Asset], &order)
+		}
+		if err != nil {
+			b.logger.Error("Failed to execute sell order", "asset", decision.Asset, "error", err)
+			b.notify(notifier.Event{Type: notifier.EventOrderFailed, Decision: &decision, Error: err.Error()})
+			result.Success = false
+			result.Error = err.Error()
+			order.Error = err.Error()
+		} else {
+			order.Success = true
+			result.TotalSold = result.TotalSold.Add(order.Amount)
+			result.RealizedPnL = result.RealizedPnL.Add(*order.RealizedPnL)
+			state.SoldInPeriod = state.SoldInPeriod.Add(order.Amount)
+			b.logger.Info("Took profit",
+				"asset", decision.Asset,
+				"proceeds", order.Amount.StringFixed(2),
+				"realized_pnl", order.RealizedPnL.StringFixed(2))
+		}
+		result.Orders = append(result.Orders, order)
+	}
+}
+
+// withoutAssets returns the decisions for assets other than those of the excluded decisions
+func withoutAssets(decisions, excluded []types.InvestmentDecision) []types.InvestmentDecision {
+	skip := make(map[string]bool)
+	for _, decision := range excluded {
+		skip[decision.Asset] = true
+	}
+
+	var kept []types.InvestmentDecision
+	for _, decision := range decisions {
+		if !skip[decision.Asset] {
+			kept = append(kept, decision)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,288 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"moonshot/notifier"
+	"moonshot/types"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// TWAP child order statuses
+const (
+	twapPending = "pending"
+	twapDone    = "done"
+	twapFailed  = "failed"
+)
+
+// ValidateTWAP checks the TWAP configuration
+func ValidateTWAP(config *types.BotConfig) error {
+	if config.TWAPSlices < 1 {
+		return fmt.Errorf("TWAP slices must be at least 1")
+	}
+	if config.TWAPSlices > 1 && config.TWAPWindow <= 0 {
+		return fmt.Errorf("TWAP window must be positive")
+	}
+	if config.TWAPMinAmount.IsNegative() {
+		return fmt.Errorf("TWAP minimum amount cannot be negative")
+	}
+	return nil
+}
+
+// useTWAP reports whether a decision is large enough to be split into child orders
+func (b *DCABot) useTWAP(decision types.InvestmentDecision) bool {
+	return b.config.TWAPSlices > 1 && decision.Amount.GreaterThanOrEqual(b.config.TWAPMinAmount)
+}
+
+// startTWAP splits a decision into equal child orders spaced evenly over the TWAP window, the first
+// due immediately, and adds the parent to the state. Children are executed by runTWAP.
+func (b *DCABot) startTWAP(decision types.InvestmentDecision, state *types.BotState) {
+	slices := b.config.TWAPSlices
+	interval := b.config.TWAPWindow / time.Duration(slices)
+	childAmount := decision.Amount.Div(decimal.NewFromInt(int64(slices))).RoundDown(2)
+	now := time.Now()
+
+	parent := types.TWAPOrder{
+		ID:           uuid.NewString(),
+		Decision:     decision,
+		ArrivalPrice: decision.Price,
+		CreatedAt:    now,
+	}
+	allocated := decimal.Zero
+	for i := 0; i < slices; i++ {
+		amount := childAmount
+		if i == slices-1 {
+			// The last child absorbs the rounding remainder
+			amount = decision.Amount.Sub(allocated)
+		}
+		allocated = allocated.Add(amount)
+
+		parent.Children = append(parent.Children, types.TWAPChild{
+			Index:  i + 1,
+			DueAt:  now.Add(time.Duration(i) * interval),
+			Amount: amount,
+			Status: twapPending,
+		})
+	}
+
+	b.logger.Info("Splitting buy into TWAP child orders",
+		"asset", decision.Asset,
+		"parent_id", parent.ID,
+		"amount", decision.Amount.String(),
+		"slices", slices,
+		"window", b.config.TWAPWindow.String())
+
+	state.TWAPOrders = append(state.TWAPOrders, parent)
+}
+
+// runTWAP executes the pending child orders of all TWAP parents that are due, in due order, saving
+// the state after each. Children due later are left in the state for ContinueTWAP. Completed parents
+// are removed from the state.
+func (b *DCABot) runTWAP(ctx context.Context, state *types.BotState) ([]types.TWAPSummary, []types.OrderResult) {
+	if len(state.TWAPOrders) == 0 {
+		return nil, nil
+	}
+
+	var orders []types.OrderResult
+	for {
+		parent, child := nextTWAPChild(state.TWAPOrders)
+		if child == nil || child.DueAt.After(time.Now()) {
+			break
+		}
+		orders = append(orders, b.executeTWAPChild(ctx, parent, child))
+		b.saveState(state)
+	}
+
+	var summaries []types.TWAPSummary
+	var pending []types.TWAPOrder
+	for _, parent := range state.TWAPOrders {
+		summary := summarizeTWAP(parent)
+		summaries = append(summaries, summary)
+		if !summary.Complete {
+			pending = append(pending, parent)
+			continue
+		}
+
+		b.logger.Info("TWAP order complete",
+			"asset", summary.Asset,
+			"parent_id", summary.ParentID,
+			"invested", summary.Invested.StringFixed(2),
+			"vwap", summary.VWAP.String(),
+			"arrival_price", summary.ArrivalPrice.String(),
+			"slippage_percent", summary.SlippagePercent.StringFixed(3))
+	}
+	state.TWAPOrders = pending
+
+	return summaries, orders
+}
+
+// NextTWAPAt returns when the next pending TWAP child order comes due, or the zero time if there is none
+func (b *DCABot) NextTWAPAt() time.Time {
+	state, err := b.GetState()
+	if err != nil {
+		return time.Time{}
+	}
+	_, child := nextTWAPChild(state.TWAPOrders)
+	if child == nil {
+		return time.Time{}
+	}
+	return child.DueAt
+}
+
+// ContinueTWAP executes the due child orders of pending TWAP parents without starting a new DCA run.
+// The daemon calls it as children come due; on Lambda it is invoked by an extra schedule during the
+// TWAP window. It fails if there is no saved state, since the pending children would be lost silently.
+func (b *DCABot) ContinueTWAP(ctx context.Context) (*types.ExecutionResult, error) {
+	return b.run(ctx, types.RunKindTWAP, b.continueTWAP)
+}
+
+// continueTWAP is the body of a TWAP continuation
+func (b *DCABot) continueTWAP(ctx context.Context, state *types.BotState) (*types.ExecutionResult, error) {
+	if state.TotalRuns == 0 && state.LastRunAt.IsZero() {
+		return nil, fmt.Errorf("no saved bot state, pending TWAP orders can't be continued; check STATE_FILE_PATH is on durable storage")
+	}
+
+	result := &types.ExecutionResult{
+		Success:   true,
+		Timestamp: time.Now(),
+	}
+	if len(state.TWAPOrders) == 0 {
+		b.logger.Info("No pending TWAP orders")
+		return result, nil
+	}
+
+	// Refresh balances for the pre-trade checks
+	portfolio, err := b.getPortfolio(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Portfolio = portfolio
+
+	result.TWAP, result.Orders = b.runTWAP(ctx, state)
+	for _, order := range result.Orders {
+		if order.Success {
+			result.TotalInvested = result.TotalInvested.Add(order.Amount)
+			result.FeeSavings = result.FeeSavings.Add(order.FeeSavings)
+		} else {
+			result.Success = false
+			result.Error = order.Error
+		}
+	}
+	return result, nil
+}
+
+// executeTWAPChild places a single child order at the current price and records its outcome on the child
+func (b *DCABot) executeTWAPChild(ctx context.Context, parent *types.TWAPOrder, child *types.TWAPChild) types.OrderResult {
+	decision := parent.Decision
+	decision.Amount = child.Amount
+	decision.Timestamp = time.Now()
+
+	// Later children are priced at the market when they execute, not when the parent was created
+	if child.Index > 1 {
+		if price, err := b.currentPrice(ctx, decision.Asset); err == nil {
+			decision.Price = price
+		} else {
+			b.logger.Warn("Failed to refresh price for TWAP child order", "asset", decision.Asset, "error", err)
+		}
+	}
+
+	b.logger.Info("Executing TWAP child order",
+		"asset", decision.Asset,
+		"parent_id", parent.ID,
+		"child", child.Index,
+		"children", len(parent.Children),
+		"amount", child.Amount.String())
+
+	order := types.OrderResult{Asset: decision.Asset, Side: "BUY", Amount: child.Amount, ParentID: parent.ID}
+	err := checkDeadline(ctx)
+	if err == nil {
+		err = b.executeBuyOrder(ctx, decision, &order)
+	}
+	if err != nil {
+		b.logger.Error("Failed to execute TWAP child order", "asset", decision.Asset, "parent_id", parent.ID, "error", err)
+		b.notify(notifier.Event{Type: notifier.EventOrderFailed, Decision: &decision, Error: err.Error()})
+		child.Status = twapFailed
+		child.Error = err.Error()
+		order.Error = err.Error()
+		return order
+	}
+
+	child.Status = twapDone
+	child.OrderID = order.OrderID
+	child.Invested = order.Amount
+	child.FilledSize = order.FilledSize
+	child.AveragePrice = order.AveragePrice
+	order.Success = true
+	return order
+}
+
+// currentPrice returns the price a market buy would pay now: the live last trade price, or the best ask
+func (b *DCABot) currentPrice(ctx context.Context, asset string) (decimal.Decimal, error) {
+	if quote, ok := b.liveQuote(asset); ok {
+		return quote.LastPrice, nil
+	}
+
+	bid, ask, err := b.coinbaseService.GetBestBidAsk(ctx, asset+"-USDC")
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if ask.IsPositive() {
+		return ask, nil
+	}
+	if bid.IsPositive() {
+		return bid, nil
+	}
+	return decimal.Zero, fmt.Errorf("no quotes in order book for %s", asset)
+}
+
+// nextTWAPChild returns the pending child order that is due first across all parents
+func nextTWAPChild(parents []types.TWAPOrder) (*types.TWAPOrder, *types.TWAPChild) {
+	var nextParent *types.TWAPOrder
+	var next *types.TWAPChild
+	for i := range parents {
+		for j := range parents[i].Children {
+			child := &parents[i].Children[j]
+			if child.Status == twapPending && (next == nil || child.DueAt.Before(next.DueAt)) {
+				nextParent, next = &parents[i], child
+			}
+		}
+	}
+	return nextParent, next
+}
+
+// summarizeTWAP computes the progress of a parent and the VWAP of its fills against the arrival price
+func summarizeTWAP(parent types.TWAPOrder) types.TWAPSummary {
+	summary := types.TWAPSummary{
+		ParentID:     parent.ID,
+		Asset:        parent.Decision.Asset,
+		Amount:       parent.Decision.Amount,
+		ArrivalPrice: parent.ArrivalPrice,
+		Children:     len(parent.Children),
+		Complete:     true,
+	}
+
+	size, value := decimal.Zero, decimal.Zero
+	for _, child := range parent.Children {
+		if child.Status == twapPending {
+			summary.Complete = false
+			continue
+		}
+		summary.ChildrenDone++
+		summary.Invested = summary.Invested.Add(child.Invested)
+		size = size.Add(child.FilledSize)
+		value = value.Add(child.FilledSize.Mul(child.AveragePrice))
+	}
+
+	if size.IsPositive() {
+		summary.VWAP = value.Div(size)
+		if parent.ArrivalPrice.IsPositive() {
+			summary.SlippagePercent = summary.VWAP.Sub(parent.ArrivalPrice).Div(parent.ArrivalPrice).Mul(decimal.NewFromInt(100))
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"moonshot/services"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Strategies deciding how much to buy each period
+const (
+	StrategyDCA            = "dca"             // Base investment scaled by the F&G and indicator multiplier
+	StrategyValueAveraging = "value_averaging" // Buy the shortfall against a growing target value
+)
+
+// maxValueAveragingPeriods bounds how many scheduled periods are counted along the target path
+const maxValueAveragingPeriods = 100000
+
+// ValidateStrategy checks the strategy and its value averaging settings
+func ValidateStrategy(config *types.BotConfig) error {
+	switch normalizeStrategy(config.Strategy) {
+	case StrategyDCA:
+		return nil
+	case StrategyValueAveraging:
+		if config.VATargetIncrement.IsNegative() {
+			return fmt.Errorf("value averaging target increment cannot be negative")
+		}
+		if !config.VATargetIncrement.IsPositive() && !config.WeeklyBaseInvestment.IsPositive() {
+			return fmt.Errorf("value averaging needs a positive target increment")
+		}
+		if config.VAGrowthPercent.LessThanOrEqual(decimal.NewFromInt(-100)) {
+			return fmt.Errorf("value averaging growth percent must be greater than -100")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported strategy: %s (must be dca or value_averaging)", config.Strategy)
+	}
+}
+
+// normalizeStrategy lower-cases the strategy and defaults it to dca
+func normalizeStrategy(strategy string) string {
+	if strategy == "" {
+		return StrategyDCA
+	}
+	return strings.ToLower(strategy)
+}
+
+// valueAveraging reports whether the value averaging strategy is configured
+func (b *DCABot) valueAveraging() bool {
+	return normalizeStrategy(b.config.Strategy) == StrategyValueAveraging
+}
+
+// valueAveragingIncrement returns the target value added in the first period, defaulting to the base investment
+func (b *DCABot) valueAveragingIncrement() decimal.Decimal {
+	if b.config.VATargetIncrement.IsPositive() {
+		return b.config.VATargetIncrement
+	}
+	return b.config.WeeklyBaseInvestment
+}
+
+// valueAveragingDecisions buys the shortfall of the invested value against the target path, split
+// by allocation and subject to the same caps as DCA. Nothing is bought when the portfolio is ahead
+// of the target. The path starts from the invested value at the first value averaging run.
+func (b *DCABot) valueAveragingDecisions(snapshot *types.MarketSnapshot, state *types.BotState, current time.Time) ([]types.InvestmentDecision, *types.ValueAverageSummary, error) {
+	value, err := b.investedValue(snapshot.Portfolio, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if state.VAStartedAt.IsZero() {
+		state.VAStartedAt = current
+		state.VAStartValue = value
+		b.logger.Info("Starting value averaging target path",
+			"started_at", current.Format(time.RFC3339), "start_value", value.StringFixed(2))
+	}
+
+	summary := &types.ValueAverageSummary{
+		StartedAt: state.VAStartedAt,
+		Period:    valueAveragingPeriod(b.schedule, state.VAStartedAt, current),
+		Value:     value,
+	}
+	summary.Target = valueAveragingTarget(state.VAStartValue, b.valueAveragingIncrement(), b.config.VAGrowthPercent, summary.Period)
+	summary.Shortfall = summary.Target.Sub(value)
+
+	b.logger.Info("Calculated value averaging target",
+		"period", summary.Period,
+		"target", summary.Target.StringFixed(2),
+		"value", value.StringFixed(2),
+		"shortfall", summary.Shortfall.StringFixed(2))
+
+	if summary.Shortfall.LessThan(minOrderAmount) {
+		b.logger.Info("Portfolio at or ahead of the value averaging target, buying nothing")
+		return nil, summary, nil
+	}
+
+	hundred := decimal.NewFromInt(100)
+	allocations := b.allocations()
+	targets := make(map[string]decimal.Decimal)
+	for _, asset := range b.configuredAssets() {
+		targets[asset] = summary.Shortfall.Mul(allocations[asset]).Div(hundred)
+	}
+
+	amounts := b.capInvestment(snapshot, targets)
+	if amounts == nil {
+		return nil, summary, nil
+	}
+
+	reason := fmt.Sprintf("Value averaging period %d: target %s USDC, value %s USDC, shortfall %s USDC",
+		summary.Period, summary.Target.StringFixed(2), value.StringFixed(2), summary.Shortfall.StringFixed(2))
+
+	var decisions []types.InvestmentDecision
+	for _, asset := range b.configuredAssets() {
+		price, ok := snapshot.Prices[asset]
+		if !ok || !amounts[asset].GreaterThan(decimal.Zero) {
+			continue
+		}
+		summary.Amount = summary.Amount.Add(amounts[asset])
+		decisions = append(decisions, types.InvestmentDecision{
+			Asset:     asset,
+			Action:    "buy",
+			Amount:    amounts[asset],
+			Price:     price,
+			Reason:    reason,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return decisions, summary, nil
+}
+
+// investedValue returns the value of the configured assets: the portfolio's total value less its
+// USDC, plus TWAP child orders still to be bought. Every asset must be valued, or the shortfall
+// would be overstated.
+func (b *DCABot) investedValue(portfolio *types.Portfolio, state *types.BotState) (decimal.Decimal, error) {
+	for _, symbol := range b.configuredAssets() {
+		if asset, ok := portfolio.Assets[symbol]; !ok || asset.Status != services.ValuationOK {
+			return decimal.Zero, fmt.Errorf("value averaging needs every asset valued, %s could not be", symbol)
+		}
+	}
+
+	value := portfolio.TotalValue.Sub(portfolio.USDCBalance).Sub(portfolio.USDCHold)
+	for _, parent := range state.TWAPOrders {
+		for _, child := range parent.Children {
+			if child.Status == twapPending {
+				value = value.Add(child.Amount)
+			}
+		}
+	}
+	return value, nil
+}
+
+// valueAveragingPeriod returns the number of scheduled periods from start through current, both included
+func valueAveragingPeriod(schedule *Schedule, start, current time.Time) int {
+	period := 1
+	for t := schedule.Next(start); !t.IsZero() && !t.After(current) && period < maxValueAveragingPeriods; t = schedule.Next(t) {
+		period++
+	}
+	return period
+}
+
+// valueAveragingTarget returns the target value after the given number of periods. The increment
+// of each period grows by growthPercent over the previous one.
+func valueAveragingTarget(startValue, increment, growthPercent decimal.Decimal, periods int) decimal.Decimal {
+	growth := decimal.NewFromInt(1).Add(growthPercent.Div(decimal.NewFromInt(100)))
+
+	target := startValue
+	for i := 0; i < periods; i++ {
+		target = target.Add(increment)
+		increment = increment.Mul(growth).Round(8)
+	}
+	return target.Round(2)
+}
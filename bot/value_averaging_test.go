@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestValueAveragingTarget(t *testing.T) {
+	d := decimal.RequireFromString
+	tests := []struct {
+		start, increment, growth decimal.Decimal
+		periods                  int
+		want                     string
+	}{
+		{d("250"), d("100"), d("0"), 0, "250"},
+		{d("500"), d("100"), d("0"), 1, "600"},
+		{d("0"), d("100"), d("0"), 3, "300"},
+		{d("0"), d("100"), d("10"), 3, "331"},   // 100 + 110 + 121
+		{d("0"), d("100"), d("-50"), 3, "175"},  // 100 + 50 + 25
+		{d("0"), d("33.333"), d("0"), 3, "100"}, // Rounded to cents
+	}
+
+	for _, tt := range tests {
+		got := valueAveragingTarget(tt.start, tt.increment, tt.growth, tt.periods)
+		if !got.Equal(d(tt.want)) {
+			t.Errorf("valueAveragingTarget(%s, %s, %s%%, %d) = %s, want %s",
+				tt.start, tt.increment, tt.growth, tt.periods, got, tt.want)
+		}
+	}
+}
+
+func TestValueAveragingPeriod(t *testing.T) {
+	schedule, err := NewSchedule(&types.BotConfig{InvestmentFrequency: "weekly", ExecutionTime: "12:00", ExecutionWeekday: "monday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC) // A Monday
+
+	if got := valueAveragingPeriod(schedule, started, started); got != 1 {
+		t.Errorf("period of the first run = %d, want 1", got)
+	}
+	// A run in the middle of the week still belongs to the first period
+	if got := valueAveragingPeriod(schedule, started, started.AddDate(0, 0, 3)); got != 1 {
+		t.Errorf("period three days in = %d, want 1", got)
+	}
+	if got := valueAveragingPeriod(schedule, started, started.AddDate(0, 0, 7)); got != 2 {
+		t.Errorf("period a week later = %d, want 2", got)
+	}
+	// Missed runs still advance the target path
+	if got := valueAveragingPeriod(schedule, started, started.AddDate(0, 0, 28)); got != 5 {
+		t.Errorf("period four weeks later = %d, want 5", got)
+	}
+}
+
+func TestValueAveragingSkipsCatchUp(t *testing.T) {
+	config := &types.BotConfig{
+		InvestmentFrequency:  FrequencyDaily,
+		ExecutionTime:        "00:00",
+		WeeklyBaseInvestment: decimal.NewFromInt(10),
+		CatchUpPolicy:        CatchUpLumpSum,
+		Strategy:             "Value_Averaging",
+	}
+	schedule, err := NewSchedule(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewDCABot(config, nil, nil, schedule, nil, nil, nil)
+
+	state := &types.BotState{LastScheduledAt: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}
+	plan := b.planCatchUp(state, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC))
+	if plan.policy != CatchUpSkip || len(plan.periods) != 0 || !plan.amount.IsZero() {
+		t.Errorf("plan = %s policy, %d periods, %s USDC, want nothing to catch up", plan.policy, len(plan.periods), plan.amount)
+	}
+}
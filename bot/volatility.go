@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"moonshot/indicators"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Volatility sizing modes
+const (
+	VolatilitySizingOff     = "off"
+	VolatilitySizingInverse = "inverse" // Buy less when volatility is above the target
+	VolatilitySizingDirect  = "direct"  // Buy more when volatility is above the target
+)
+
+// ValidateVolatilitySizing checks the volatility sizing configuration
+func ValidateVolatilitySizing(config *types.BotConfig) error {
+	switch normalizeVolatilitySizing(config.VolatilitySizing) {
+	case VolatilitySizingOff:
+		return nil
+	case VolatilitySizingInverse, VolatilitySizingDirect:
+		if config.VolatilityLookback < 2 {
+			return fmt.Errorf("volatility lookback must be at least 2 days")
+		}
+		if !config.VolatilityTarget.IsPositive() {
+			return fmt.Errorf("volatility target must be positive")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported volatility sizing: %s (must be off, inverse or direct)", config.VolatilitySizing)
+	}
+}
+
+// normalizeVolatilitySizing lower-cases the mode and defaults it to off
+func normalizeVolatilitySizing(mode string) string {
+	if mode == "" {
+		return VolatilitySizingOff
+	}
+	return strings.ToLower(mode)
+}
+
+// usesVolatilitySizing reports whether the multiplier is scaled by realized volatility
+func usesVolatilitySizing(config *types.BotConfig) bool {
+	return normalizeVolatilitySizing(config.VolatilitySizing) != VolatilitySizingOff
+}
+
+// sizedMultiplier returns an asset's signal multiplier scaled by its realized volatility, along with
+// each signal's multiplier and the volatility adjustment, if any
+func (b *DCABot) sizedMultiplier(snapshot *types.MarketSnapshot, asset string) (decimal.Decimal, map[string]decimal.Decimal, *types.VolatilityAdjustment) {
+	multiplier, signals := b.assetMultiplier(snapshot, asset)
+	if !usesVolatilitySizing(b.config) {
+		return multiplier, signals, nil
+	}
+
+	realized, ok := snapshot.Indicators[asset][indicators.RealizedVolatility]
+	if !ok || !realized.IsPositive() {
+		return multiplier, signals, nil
+	}
+
+	adjustment := &types.VolatilityAdjustment{
+		Mode:            normalizeVolatilitySizing(b.config.VolatilitySizing),
+		RealizedPercent: realized.Round(2),
+		TargetPercent:   b.config.VolatilityTarget,
+		BaseMultiplier:  multiplier,
+	}
+	if adjustment.Mode == VolatilitySizingInverse {
+		adjustment.Factor = b.config.VolatilityTarget.Div(realized).Round(4)
+	} else {
+		adjustment.Factor = realized.Div(b.config.VolatilityTarget).Round(4)
+	}
+
+	// The combined multiplier stays within the configured bounds
+	sized := multiplier.Mul(adjustment.Factor)
+	bounded := decimal.Min(decimal.Max(sized, b.config.MinMultiplier), b.config.MaxMultiplier)
+	adjustment.Clamped = !bounded.Equal(sized)
+
+	return bounded.Round(4), signals, adjustment
+}
+
+// volatilityReason describes a volatility adjustment for a decision's reason
+func volatilityReason(adjustment *types.VolatilityAdjustment) string {
+	reason := fmt.Sprintf(", %s volatility sizing: realized %s%% vs %s%% target scales %s by %s",
+		adjustment.Mode, adjustment.RealizedPercent.StringFixed(2), adjustment.TargetPercent.String(),
+		adjustment.BaseMultiplier.String(), adjustment.Factor.String())
+	if adjustment.Clamped {
+		reason += " (held within multiplier bounds)"
+	}
+	return reason
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// runBackfill imports historical Coinbase fills into the trade ledger
+func runBackfill(args []string) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	products := flags.String("products", "BTC-USDC,ETH-USDC", "comma separated products to backfill")
+	since := flags.String("since", "", "only import fills on or after this date (YYYY-MM-DD)")
+	dryRun := flags.Bool("dry-run", false, "fetch and report fills without writing the ledger")
+	flags.Parse(args)
+
+	var sinceTime time.Time
+	if *since != "" {
+		var err error
+		sinceTime, err = time.Parse("2006-01-02", *since)
+		if err != nil {
+			fatal("Invalid -since date", err)
+		}
+	}
+
+	productIDs := strings.Split(*products, ",")
+	if *dryRun {
+		entries, err := dcaBot.ImportFills(context.Background(), productIDs, sinceTime)
+		if err != nil {
+			fatal("Backfill failed", err)
+		}
+		slog.Info("Dry run, ledger not modified", "fills", len(entries))
+		return
+	}
+
+	entries, err := dcaBot.Backfill(context.Background(), productIDs, sinceTime)
+	if err != nil {
+		fatal("Backfill failed", err)
+	}
+	slog.Info("Backfill complete", "fills", len(entries))
+}
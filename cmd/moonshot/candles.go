@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"moonshot/services"
+	"moonshot/store"
+)
+
+// runCandles fetches Coinbase price history into the local candle store, or imports it from CSV
+func runCandles(args []string) {
+	if len(args) == 0 {
+		fatal("Missing candles command", fmt.Errorf("usage: candles fetch|import [flags]"))
+	}
+
+	switch args[0] {
+	case "fetch":
+		runCandlesFetch(args[1:])
+	case "import":
+		runCandlesImport(args[1:])
+	default:
+		fatal("Unknown candles command", fmt.Errorf("%q is not fetch or import", args[0]))
+	}
+}
+
+// runCandlesFetch fills the gaps in the stored history of each product since a date
+func runCandlesFetch(args []string) {
+	flags := flag.NewFlagSet("candles fetch", flag.ExitOnError)
+	products := flags.String("products", "BTC-USDC,ETH-USDC", "comma separated products to fetch")
+	granularity := flags.String("granularity", services.GranularityOneDay, "candle granularity, e.g. ONE_HOUR or ONE_DAY")
+	since := flags.String("since", "", "fetch candles starting on or after this date (YYYY-MM-DD)")
+	flags.Parse(args)
+
+	if *since == "" {
+		fatal("Missing -since date", fmt.Errorf("-since is required"))
+	}
+	start, err := time.Parse("2006-01-02", *since)
+	if err != nil {
+		fatal("Invalid -since date", err)
+	}
+
+	setupCoinbase()
+	for _, productID := range strings.Split(*products, ",") {
+		added, err := candleHistory.Fill(context.Background(), productID, *granularity, start, time.Now())
+		if err != nil {
+			fatal("Failed to fetch candles", err)
+		}
+		slog.Info("Candles fetched", "product_id", productID, "granularity", *granularity, "added", added)
+	}
+}
+
+// runCandlesImport merges candles from a CSV file into the store, e.g. a checked-in dataset for
+// offline backtests
+func runCandlesImport(args []string) {
+	flags := flag.NewFlagSet("candles import", flag.ExitOnError)
+	input := flags.String("file", "", "CSV file with start, open, high, low, close and optional volume columns")
+	productID := flags.String("product", "", "product the candles belong to, e.g. BTC-USDC")
+	granularity := flags.String("granularity", services.GranularityOneDay, "candle granularity, e.g. ONE_HOUR or ONE_DAY")
+	flags.Parse(args)
+
+	if *input == "" || *productID == "" {
+		fatal("Missing import flags", fmt.Errorf("-file and -product are required"))
+	}
+	if _, err := services.GranularityDuration(*granularity); err != nil {
+		fatal("Invalid granularity", err)
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		fatal("Failed to open candle file", err)
+	}
+	candles, err := store.ReadCandlesCSV(file)
+	file.Close()
+	if err != nil {
+		fatal("Invalid candle file", err)
+	}
+
+	if err := candleStore.Put(*productID, *granularity, candles); err != nil {
+		fatal("Failed to import candles", err)
+	}
+	slog.Info("Candles imported", "product_id", *productID, "granularity", *granularity, "candles", len(candles))
+}
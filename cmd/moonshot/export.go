@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"moonshot/tax"
+)
+
+// runExport writes tax lots or a Form 8949 layout built from the trade ledger
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	method := flags.String("method", tax.MethodFIFO, "lot matching method: fifo, lifo, hifo or specific_id")
+	format := flags.String("format", "lots", "output layout: lots or 8949")
+	output := flags.String("output", "", "output file (default stdout)")
+	year := flags.Int("year", 0, "only include lots acquired and sales made in this tax year")
+	specificIDs := flags.String("specific-ids", "", "CSV of sale_id,lot_id assignments for specific_id")
+	importFills := flags.Bool("import-fills", false, "include Coinbase fills for trades made outside the bot")
+	products := flags.String("products", "BTC-USDC,ETH-USDC,BTC-USD,ETH-USD", "products to import fills for")
+	since := flags.String("since", "", "only import fills on or after this date (YYYY-MM-DD)")
+	flags.Parse(args)
+
+	var specificLots map[string][]string
+	if *specificIDs != "" {
+		file, err := os.Open(*specificIDs)
+		if err != nil {
+			fatal("Failed to open specific lot assignments", err)
+		}
+		specificLots, err = tax.ReadSpecificLots(file)
+		file.Close()
+		if err != nil {
+			fatal("Invalid specific lot assignments", err)
+		}
+	}
+
+	tracker, err := tax.NewLotTracker(*method, specificLots)
+	if err != nil {
+		fatal("Invalid lot matching method", err)
+	}
+
+	entries, err := ledger.Entries()
+	if err != nil {
+		fatal("Failed to read ledger", err)
+	}
+
+	if *importFills {
+		setupBot()
+
+		var sinceTime time.Time
+		if *since != "" {
+			sinceTime, err = time.Parse("2006-01-02", *since)
+			if err != nil {
+				fatal("Invalid -since date", err)
+			}
+		}
+
+		imported, err := dcaBot.ImportFills(context.Background(), strings.Split(*products, ","), sinceTime)
+		if err != nil {
+			fatal("Failed to import fills", err)
+		}
+		entries = append(entries, imported...)
+	}
+
+	lots, disposals, err := tracker.Process(entries)
+	if err != nil {
+		fatal("Failed to build tax lots", err)
+	}
+	lots, disposals = tax.FilterYear(lots, disposals, *year)
+
+	var writer io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fatal("Failed to create output file", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	switch *format {
+	case "lots":
+		err = tax.WriteLotsCSV(writer, lots)
+	case "8949":
+		err = tax.WriteForm8949CSV(writer, disposals)
+	default:
+		err = fmt.Errorf("unsupported export format: %s", *format)
+	}
+	if err != nil {
+		fatal("Failed to write export", err)
+	}
+
+	if *output != "" {
+		slog.Info("Export written", "lots", len(lots), "disposals", len(disposals), "output", *output)
+	}
+}
@@ -3,15 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
+	"moonshot/backtest"
 	"moonshot/bot"
+	"moonshot/logging"
+	"moonshot/metrics"
+	"moonshot/notifier"
 	"moonshot/services"
+	"moonshot/store"
 	"moonshot/types"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/shopspring/decimal"
 )
 
 // LambdaResponse represents the Lambda response
@@ -23,47 +34,161 @@ type LambdaResponse struct {
 	Timestamp time.Time   `json:"timestamp"`
 }
 
-// Global bot instance
-var dcaBot *bot.DCABot
+// Global bot instance and the services it uses. The bot and the Coinbase service need credentials
+// and are only set up for the commands that trade; the offline data is always available.
+var (
+	dcaBot          *bot.DCABot
+	coinbaseService *services.CoinbaseService
+	candleHistory   *services.CandleHistory
+)
+
+// Offline data: the trade ledger, the local price history and the Fear & Greed history
+var (
+	fngService     *services.FNGService
+	ledger         *store.FileLedger
+	ledgerPath     string
+	candleStore    *store.FileCandleStore
+	fngHistoryPath string
+)
+
+// logCloser releases the log file when logging to a file
+var logCloser io.Closer
 
-// init initializes the bot when Lambda container starts
+// init configures logging and the offline data stores, which every command can use without
+// Coinbase credentials or a valid trading configuration
 func init() {
-	log.Println("Initializing Moonshot DCA Bot...")
+	// Configure logging before anything else logs
+	closer, err := logging.Setup(loadLoggingConfigFromEnv())
+	if err != nil {
+		fatal("Failed to configure logging", err)
+	}
+	logCloser = closer
+
+	fngService = services.NewFNGService("https://api.alternative.me/fng/")
+	fngService.SetRetryPolicy(loadRetryPolicyFromEnv())
+
+	ledgerPath = getEnvString("LEDGER_FILE_PATH", defaultDataPath("moonshot-ledger.jsonl"))
+	ledger = store.NewFileLedger(ledgerPath)
+
+	// Price history is cached locally so only missing candles are fetched
+	candleStore = store.NewFileCandleStore(getEnvString("CANDLE_STORE_DIR", defaultDataPath("candles")))
+	fngHistoryPath = getEnvString("FNG_HISTORY_PATH", defaultDataPath("fng-history.csv"))
+}
+
+// setupCoinbase loads the Coinbase credentials and creates the Coinbase service and the candle
+// history that fetches missing candles through it
+func setupCoinbase() {
+	coinbaseConfig, err := loadCoinbaseConfigFromEnv()
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	if err := validateCoinbaseConfig(coinbaseConfig); err != nil {
+		fatal("Configuration validation failed", err)
+	}
+
+	coinbaseService = services.NewCoinbaseService(coinbaseConfig)
+	coinbaseService.SetRetryPolicy(loadRetryPolicyFromEnv())
+	candleHistory = services.NewCandleHistory(coinbaseService, candleStore)
+}
+
+// setupBot loads and validates the trading configuration and initializes the bot
+func setupBot() {
+	slog.Info("Initializing Moonshot DCA Bot")
 
 	// Load configuration from environment variables
-	botConfig, coinbaseConfig, err := loadConfigFromEnv()
+	botConfig, err := loadBotConfigFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", err)
 	}
 
 	// Validate configuration
-	if err := validateConfig(botConfig, coinbaseConfig); err != nil {
-		log.Fatalf("Configuration validation failed: %v", err)
+	if err := validateConfig(botConfig); err != nil {
+		fatal("Configuration validation failed", err)
 	}
 
-	log.Printf("Configuration loaded successfully")
-	log.Printf("BTC Allocation: %s%%", botConfig.BTCAllocation.String())
-	log.Printf("ETH Allocation: %s%%", botConfig.ETHAllocation.String())
-	log.Printf("Weekly Base Investment: %s USDC", botConfig.WeeklyBaseInvestment.String())
+	slog.Info("Configuration loaded",
+		"btc_allocation", botConfig.BTCAllocation.String(),
+		"eth_allocation", botConfig.ETHAllocation.String(),
+		"weekly_base_investment", botConfig.WeeklyBaseInvestment.String(),
+		"catch_up_policy", botConfig.CatchUpPolicy)
 
 	// Initialize services
-	coinbaseService := services.NewCoinbaseService(coinbaseConfig)
-	fngService := services.NewFNGService("https://api.alternative.me/fng/")
+	setupCoinbase()
+
+	// Initialize schedule and state persistence
+	schedule, err := bot.NewSchedule(botConfig)
+	if err != nil {
+		fatal("Invalid schedule", err)
+	}
+	statePath := getEnvString("STATE_FILE_PATH", defaultDataPath("moonshot-state.json"))
+	if err := checkDurableState(botConfig, statePath, ledgerPath); err != nil {
+		fatal("State is not persisted across cold starts", err)
+	}
+	stateStore := store.NewFileStateStore(statePath)
+
+	// Initialize notifications
+	notifications := notifier.NewFromConfig(loadNotificationConfigFromEnv())
+	if notifications.Enabled() {
+		slog.Info("Notifications enabled")
+	}
 
 	// Initialize bot
-	dcaBot = bot.NewDCABot(botConfig, coinbaseService, fngService)
+	dcaBot = bot.NewDCABot(botConfig, coinbaseService, fngService, schedule, stateStore, ledger, notifications)
+	dcaBot.SetCandleSource(candleHistory)
+
+	slog.Info("Moonshot DCA Bot initialized")
+}
+
+// loadBacktestDefaults returns the live configuration as default backtest parameters. Only the
+// configuration is read; it is validated by the backtest, not as a trading configuration.
+func loadBacktestDefaults() backtest.Params {
+	botConfig, err := loadBotConfigFromEnv()
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	return defaultBacktestParams(botConfig)
+}
 
-	log.Println("Moonshot DCA Bot initialized successfully")
+// loadRetryPolicyFromEnv returns the backoff used to retry transient API failures
+func loadRetryPolicyFromEnv() services.RetryPolicy {
+	retryPolicy := services.DefaultRetryPolicy()
+	retryPolicy.MaxRetries = getEnvInt("MAX_RETRIES", retryPolicy.MaxRetries)
+	retryPolicy.BaseDelay = time.Duration(getEnvInt("RETRY_BASE_DELAY_MS", int(retryPolicy.BaseDelay.Milliseconds()))) * time.Millisecond
+	return retryPolicy
 }
 
-// handleRequest handles EventBridge scheduler triggers
+// handleRequest handles EventBridge scheduler triggers. An event with {"action": "twap"} only
+// continues pending TWAP orders, and {"action": "dip_check"} only checks for dips, instead of
+// starting a DCA run.
 func handleRequest(ctx context.Context, event interface{}) (LambdaResponse, error) {
-	log.Println("EventBridge trigger received - executing DCA bot...")
+	var result *types.ExecutionResult
+	var err error
+	kind := types.RunKindDCA
+	payload, _ := event.(map[string]interface{})
+	switch payload["action"] {
+	case "twap":
+		slog.Info("EventBridge trigger received, continuing TWAP orders")
+		kind = types.RunKindTWAP
+		result, err = dcaBot.ContinueTWAP(ctx)
+	case "dip_check":
+		slog.Info("EventBridge trigger received, checking for dips")
+		kind = types.RunKindDipCheck
+		result, err = dcaBot.CheckDips(ctx)
+	default:
+		slog.Info("EventBridge trigger received, executing DCA bot")
+		result, err = dcaBot.Execute(ctx)
+	}
+
+	// Emit execution metrics as CloudWatch EMF log lines
+	if getEnvBool("EMF_ENABLED", true) {
+		emf := metrics.NewEMFWriter(os.Stdout, getEnvString("EMF_NAMESPACE", metrics.DefaultEMFNamespace))
+		if err := emf.WriteRun(kind, result, err); err != nil {
+			slog.Warn("Failed to emit EMF metrics", "error", err)
+		}
+	}
 
-	// Execute the DCA bot logic
-	result, err := dcaBot.Execute()
 	if err != nil {
-		log.Printf("Bot execution failed: %v", err)
+		slog.Error("Bot execution failed", "error", err)
 		return LambdaResponse{
 			Success:   false,
 			Message:   "Bot execution failed",
@@ -72,9 +197,11 @@ func handleRequest(ctx context.Context, event interface{}) (LambdaResponse, erro
 		}, nil
 	}
 
-	log.Printf("Bot execution completed successfully")
-	log.Printf("Total invested: %s USDC", result.TotalInvested.String())
-	log.Printf("Total sold: %s USDC", result.TotalSold.String())
+	slog.Info("Bot execution completed",
+		"run_id", result.RunID,
+		"success", result.Success,
+		"total_invested", result.TotalInvested.String(),
+		"total_sold", result.TotalSold.String())
 
 	return LambdaResponse{
 		Success:   result.Success,
@@ -84,8 +211,8 @@ func handleRequest(ctx context.Context, event interface{}) (LambdaResponse, erro
 	}, nil
 }
 
-// loadConfigFromEnv loads configuration from environment variables
-func loadConfigFromEnv() (*types.BotConfig, *types.CoinbaseConfig, error) {
+// loadBotConfigFromEnv loads the bot configuration from environment variables
+func loadBotConfigFromEnv() (*types.BotConfig, error) {
 	// Load bot configuration
 	botConfig := &types.BotConfig{}
 
@@ -95,7 +222,7 @@ func loadConfigFromEnv() (*types.BotConfig, *types.CoinbaseConfig, error) {
 
 	// Validate allocations sum to 100
 	if btcAlloc+ethAlloc != 100.0 {
-		return nil, nil, fmt.Errorf("BTC and ETH allocations must sum to 100, got %.1f + %.1f", btcAlloc, ethAlloc)
+		return nil, fmt.Errorf("BTC and ETH allocations must sum to 100, got %.1f + %.1f", btcAlloc, ethAlloc)
 	}
 
 	botConfig.BTCAllocation = types.DecimalFromFloat(btcAlloc)
@@ -107,24 +234,132 @@ func loadConfigFromEnv() (*types.BotConfig, *types.CoinbaseConfig, error) {
 	botConfig.MaxMultiplier = types.DecimalFromFloat(getEnvFloat("MAX_MULTIPLIER", 2.0))
 	botConfig.InvestmentFrequency = getEnvString("INVESTMENT_FREQUENCY", "weekly")
 	botConfig.ExecutionTime = getEnvString("EXECUTION_TIME", "09:00")
+	botConfig.ExecutionWeekday = getEnvString("EXECUTION_WEEKDAY", "monday")
+	botConfig.ExecutionDayOfMonth = getEnvInt("EXECUTION_DAY_OF_MONTH", 1)
+	botConfig.Timezone = getEnvString("TIMEZONE", "UTC")
+
+	// Missed-run catch-up and risk caps
+	botConfig.CatchUpPolicy = getEnvString("CATCH_UP_POLICY", bot.CatchUpSkip)
+	botConfig.CatchUpSpreadRuns = getEnvInt("CATCH_UP_SPREAD_RUNS", 4)
+	botConfig.MaxDailyInvestment = types.DecimalFromFloat(getEnvFloat("MAX_DAILY_INVESTMENT", 0))
+	botConfig.StrictValuation = getEnvBool("STRICT_VALUATION", false)
+	botConfig.MaxPriceDeviation = types.DecimalFromFloat(getEnvFloat("MAX_PRICE_DEVIATION", 5))
+
+	// Order execution
+	botConfig.ExecutionMode = getEnvString("EXECUTION_MODE", bot.ExecutionModeMarket)
+	botConfig.LimitOrderTimeout = time.Duration(getEnvInt("LIMIT_ORDER_TIMEOUT_SECONDS", 120)) * time.Second
+	botConfig.LimitMaxReprices = getEnvInt("LIMIT_MAX_REPRICES", 2)
+	botConfig.LimitPriceTicks = getEnvInt("LIMIT_PRICE_TICKS", 0)
+	botConfig.LimitMarketFallback = getEnvBool("LIMIT_MARKET_FALLBACK", true)
+
+	// Ladder of limit buys below market from the dip buying buffer
+	botConfig.LadderEnabled = getEnvBool("LADDER_ENABLED", false)
+	ladderLevels, err := getEnvDecimals("LADDER_LEVELS", "5,10,15")
+	if err != nil {
+		return nil, err
+	}
+	botConfig.LadderLevels = ladderLevels
+	botConfig.LadderBufferShare = types.DecimalFromFloat(getEnvFloat("LADDER_BUFFER_SHARE", 50))
+
+	// TWAP splitting of large buys
+	botConfig.TWAPSlices = getEnvInt("TWAP_SLICES", 1)
+	botConfig.TWAPWindow = time.Duration(getEnvInt("TWAP_WINDOW_MINUTES", 60)) * time.Minute
+	botConfig.TWAPMinAmount = types.DecimalFromFloat(getEnvFloat("TWAP_MIN_AMOUNT", 0))
+
+	// Dip buys between scheduled runs
+	botConfig.DipTriggerEnabled = getEnvBool("DIP_TRIGGER_ENABLED", false)
+	botConfig.DipCheckInterval = time.Duration(getEnvInt("DIP_CHECK_INTERVAL_MINUTES", 15)) * time.Minute
+	botConfig.DipDrawdownPercent = types.DecimalFromFloat(getEnvFloat("DIP_DRAWDOWN_PERCENT", 10))
+	botConfig.DipLookback = time.Duration(getEnvInt("DIP_LOOKBACK_DAYS", 7)) * 24 * time.Hour
+	botConfig.DipBuyPercent = types.DecimalFromFloat(getEnvFloat("DIP_BUY_PERCENT", 25))
+	botConfig.DipCooldown = time.Duration(getEnvInt("DIP_COOLDOWN_HOURS", 24)) * time.Hour
+	botConfig.DipMaxBuysPerPeriod = getEnvInt("DIP_MAX_BUYS_PER_PERIOD", 2)
+	botConfig.DipMaxAmountPerPeriod = types.DecimalFromFloat(getEnvFloat("DIP_MAX_AMOUNT_PER_PERIOD", 0))
+
+	// Strategy deciding how much to buy each period
+	botConfig.Strategy = getEnvString("STRATEGY", bot.StrategyDCA)
+	botConfig.VATargetIncrement = types.DecimalFromFloat(getEnvFloat("VA_TARGET_INCREMENT", 0))
+	botConfig.VAGrowthPercent = types.DecimalFromFloat(getEnvFloat("VA_GROWTH_PERCENT", 0))
+
+	// Scaling of the multiplier by realized volatility
+	botConfig.VolatilitySizing = getEnvString("VOLATILITY_SIZING", bot.VolatilitySizingOff)
+	botConfig.VolatilityLookback = getEnvInt("VOLATILITY_LOOKBACK_DAYS", 30)
+	botConfig.VolatilityTarget = types.DecimalFromFloat(getEnvFloat("VOLATILITY_TARGET_PERCENT", 60))
+
+	// Signals combined into the investment multiplier
+	botConfig.FNGWeight = types.DecimalFromFloat(getEnvFloat("SIGNAL_WEIGHT_FNG", 1))
+	botConfig.RSIWeight = types.DecimalFromFloat(getEnvFloat("SIGNAL_WEIGHT_RSI", 0))
+	botConfig.MayerWeight = types.DecimalFromFloat(getEnvFloat("SIGNAL_WEIGHT_MAYER", 0))
+	botConfig.ATHWeight = types.DecimalFromFloat(getEnvFloat("SIGNAL_WEIGHT_ATH", 0))
+	botConfig.IndicatorHistory = time.Duration(getEnvInt("INDICATOR_HISTORY_DAYS", 1460)) * 24 * time.Hour
+
+	// Take-profit selling back to USDC
+	botConfig.TakeProfitEnabled = getEnvBool("TAKE_PROFIT_ENABLED", false)
+	botConfig.TakeProfitCondition = getEnvString("TAKE_PROFIT_CONDITION", bot.TakeProfitAny)
+	botConfig.FNGSellThreshold = getEnvInt("FNG_SELL_THRESHOLD", 75)
+	botConfig.TakeProfitPercentage = types.DecimalFromFloat(getEnvFloat("TAKE_PROFIT_PERCENTAGE", 0))
+	botConfig.SellPercentage = types.DecimalFromFloat(getEnvFloat("SELL_PERCENTAGE", 10))
+	botConfig.MaxSellPerPeriod = types.DecimalFromFloat(getEnvFloat("MAX_SELL_PER_PERIOD", 0))
+
+	return botConfig, nil
+}
 
+// loadCoinbaseConfigFromEnv loads the Coinbase credentials and API settings from environment variables
+func loadCoinbaseConfigFromEnv() (*types.CoinbaseConfig, error) {
 	// Load Coinbase configuration using the new credential loading method
 	creds, err := services.LoadCredentialsFromEnv()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to load Coinbase credentials: %w", err)
+		return nil, fmt.Errorf("failed to load Coinbase credentials: %w", err)
 	}
 
 	coinbaseConfig := &types.CoinbaseConfig{
-		APIKey:    creds.AccessKey,
-		APISecret: creds.PrivatePemKey,
-		Sandbox:   getEnvBool("COINBASE_SANDBOX", false),
+		APIKey:         creds.AccessKey,
+		APISecret:      creds.PrivatePemKey,
+		Sandbox:        getEnvBool("COINBASE_SANDBOX", false),
+		RequestTimeout: time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 15)) * time.Second,
+		PriceSource:    getEnvString("PRICE_SOURCE", services.PriceSourceBid),
+		MaxConcurrency: getEnvInt("MAX_CONCURRENCY", 4),
+	}
+
+	return coinbaseConfig, nil
+}
+
+// loadNotificationConfigFromEnv loads notification channel settings from environment variables
+func loadNotificationConfigFromEnv() *types.NotificationConfig {
+	var recipients []string
+	for _, recipient := range strings.Split(getEnvString("EMAIL_RECIPIENTS", ""), ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
 	}
 
-	return botConfig, coinbaseConfig, nil
+	return &types.NotificationConfig{
+		Enabled:          getEnvBool("NOTIFICATIONS_ENABLED", false),
+		SlackWebhookURL:  getEnvString("SLACK_WEBHOOK_URL", ""),
+		TelegramBotToken: getEnvString("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:   getEnvString("TELEGRAM_CHAT_ID", ""),
+		SMTPServer:       getEnvString("SMTP_SERVER", ""),
+		SMTPPort:         getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:     getEnvString("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnvString("SMTP_PASSWORD", ""),
+		EmailFrom:        getEnvString("EMAIL_FROM", ""),
+		EmailRecipients:  recipients,
+		WebhookURL:       getEnvString("WEBHOOK_URL", ""),
+	}
 }
 
-// validateConfig validates the loaded configuration
-func validateConfig(botConfig *types.BotConfig, coinbaseConfig *types.CoinbaseConfig) error {
+// loadLoggingConfigFromEnv loads logging settings from environment variables
+func loadLoggingConfigFromEnv() *types.LoggingConfig {
+	return &types.LoggingConfig{
+		Level:    getEnvString("LOG_LEVEL", "info"),
+		Format:   getEnvString("LOG_FORMAT", "text"),
+		Output:   getEnvString("LOG_OUTPUT", "stdout"),
+		FilePath: getEnvString("LOG_FILE_PATH", defaultDataPath("moonshot.log")),
+	}
+}
+
+// validateConfig validates the loaded bot configuration
+func validateConfig(botConfig *types.BotConfig) error {
 	// Validate bot configuration
 	if botConfig.WeeklyBaseInvestment.LessThanOrEqual(types.DecimalZero()) {
 		return fmt.Errorf("weekly base investment must be positive")
@@ -146,6 +381,65 @@ func validateConfig(botConfig *types.BotConfig, coinbaseConfig *types.CoinbaseCo
 		return fmt.Errorf("minimum multiplier cannot be greater than maximum multiplier")
 	}
 
+	if _, err := bot.NewSchedule(botConfig); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	if err := bot.ValidateCatchUpPolicy(botConfig); err != nil {
+		return err
+	}
+
+	if botConfig.MaxDailyInvestment.LessThan(types.DecimalZero()) {
+		return fmt.Errorf("max daily investment cannot be negative")
+	}
+
+	if botConfig.MaxPriceDeviation.LessThan(types.DecimalZero()) {
+		return fmt.Errorf("max price deviation cannot be negative")
+	}
+
+	if err := bot.ValidateExecutionMode(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateLadder(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateTWAP(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateDipTrigger(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateStrategy(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateSignalWeights(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateVolatilitySizing(botConfig); err != nil {
+		return err
+	}
+
+	if err := bot.ValidateTakeProfit(botConfig); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateCoinbaseConfig validates the loaded Coinbase configuration
+func validateCoinbaseConfig(coinbaseConfig *types.CoinbaseConfig) error {
+	switch coinbaseConfig.PriceSource {
+	case services.PriceSourceBid, services.PriceSourceAsk, services.PriceSourceMid:
+	default:
+		return fmt.Errorf("unsupported price source: %s (must be bid, ask or mid)", coinbaseConfig.PriceSource)
+	}
+
 	return nil
 }
 
@@ -184,7 +478,115 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
-// main function for Lambda
+// getEnvDecimals parses a comma-separated list of numbers
+func getEnvDecimals(key, defaultValue string) ([]decimal.Decimal, error) {
+	var values []decimal.Decimal
+	for _, field := range strings.Split(getEnvString(key, defaultValue), ",") {
+		value, err := decimal.NewFromString(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", key, field, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// fatal logs an unrecoverable error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// defaultDataPath returns the default location for a data file; only /tmp is writable on Lambda
+func defaultDataPath(name string) string {
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		return "/tmp/" + name
+	}
+	return name
+}
+
+// ephemeralPath reports whether a data file is lost on a Lambda cold start, i.e. it lives in /tmp
+func ephemeralPath(path string) bool {
+	return os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" && strings.HasPrefix(filepath.Clean(path), "/tmp/")
+}
+
+// checkDurableState refuses features that depend on the state surviving between invocations when
+// the state file is on Lambda's ephemeral /tmp, and warns about what else is lost on cold starts
+func checkDurableState(config *types.BotConfig, statePath, ledgerPath string) error {
+	if ephemeralPath(statePath) {
+		if features := bot.StatefulFeatures(config); len(features) > 0 {
+			return fmt.Errorf("%s need STATE_FILE_PATH on durable storage such as EFS, not %s",
+				strings.Join(features, ", "), statePath)
+		}
+		slog.Warn("State file is lost on Lambda cold starts; missed runs won't be detected, point STATE_FILE_PATH at durable storage such as EFS",
+			"path", statePath)
+	}
+	if ephemeralPath(ledgerPath) {
+		slog.Warn("Trade ledger is lost on Lambda cold starts; performance and take-profit use only the trades since, point LEDGER_FILE_PATH at durable storage such as EFS",
+			"path", ledgerPath)
+	}
+	return nil
+}
+
+// runDaemon runs the bot as a long-lived process using the built-in scheduler
+func runDaemon() {
+	scheduler := bot.NewScheduler(dcaBot)
+
+	// Stop scheduling new runs on SIGINT/SIGTERM; an in-flight run is allowed to finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if getEnvBool("METRICS_ENABLED", true) {
+		server := startMetricsServer(getEnvString("METRICS_ADDR", ":9090"))
+		defer server.Shutdown(context.Background())
+	}
+
+	if getEnvBool("MARKET_DATA_ENABLED", true) {
+		feed := services.NewMarketDataService(getEnvString("MARKET_DATA_URL", services.DefaultMarketDataURL), []string{"BTC-USDC", "ETH-USDC"})
+		go feed.Run(ctx)
+		dcaBot.SetPriceFeed(feed)
+	}
+
+	slog.Info("Starting Moonshot DCA Bot in daemon mode")
+	if err := scheduler.Run(ctx); err != nil {
+		fatal("Scheduler failed", err)
+	}
+	slog.Info("Moonshot DCA Bot shut down gracefully")
+}
+
+// main function for Lambda, daemon mode and CLI commands
 func main() {
+	defer logCloser.Close()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "daemon":
+			setupBot()
+			runDaemon()
+			return
+		case "performance":
+			setupBot()
+			runPerformance(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "backfill":
+			setupBot()
+			runBackfill(os.Args[2:])
+			return
+		case "candles":
+			runCandles(os.Args[2:])
+			return
+		case "sweep":
+			runSweep(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		}
+	}
+
+	setupBot()
 	lambda.Start(handleRequest)
 }
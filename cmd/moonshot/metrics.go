@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"moonshot/metrics"
+)
+
+// startMetricsServer exposes Prometheus metrics on /metrics and wires the bot and services to record them
+func startMetricsServer(addr string) *http.Server {
+	m := metrics.New()
+	dcaBot.SetRunObserver(m.ObserveRun)
+	coinbaseService.SetLatencyObserver(m.ObserveAPILatency)
+	fngService.SetLatencyObserver(m.ObserveAPILatency)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		slog.Info("Serving metrics", "addr", addr, "path", "/metrics")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics server failed", "error", err)
+		}
+	}()
+
+	return server
+}
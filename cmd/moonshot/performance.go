@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"moonshot/types"
+)
+
+// runPerformance prints cost basis, P&L and returns per asset from the trade ledger
+func runPerformance(args []string) {
+	flags := flag.NewFlagSet("performance", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+
+	report, err := dcaBot.PerformanceReport(context.Background())
+	if err != nil {
+		fatal("Failed to compute performance", err)
+	}
+	if report == nil {
+		fmt.Println("No trades recorded in the ledger yet")
+		return
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fatal("Failed to encode performance report", err)
+		}
+		return
+	}
+
+	printPerformance(report)
+}
+
+// printPerformance renders the performance report as a table
+func printPerformance(report *types.PerformanceReport) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "Asset\tQuantity\tAvg Cost\tInvested\tValue\tUnrealized P&L\tP&L %\tTWR %\tXIRR %\t")
+
+	symbols := make([]string, 0, len(report.Assets))
+	for symbol := range report.Assets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	rows := make([]*types.AssetPerformance, 0, len(symbols)+1)
+	for _, symbol := range symbols {
+		rows = append(rows, report.Assets[symbol])
+	}
+	rows = append(rows, report.Overall)
+
+	for _, perf := range rows {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			perf.Asset,
+			perf.Quantity.StringFixed(8),
+			perf.AverageCost.StringFixed(2),
+			perf.TotalInvested.StringFixed(2),
+			perf.CurrentValue.StringFixed(2),
+			perf.UnrealizedPnL.StringFixed(2),
+			perf.UnrealizedPnLPercent.StringFixed(2),
+			perf.TimeWeightedReturn.StringFixed(2),
+			perf.MoneyWeightedReturn.StringFixed(2))
+	}
+
+	writer.Flush()
+	fmt.Printf("\nAs of %s\n", report.AsOf.Format("2006-01-02 15:04:05 MST"))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"moonshot/backtest"
+	"moonshot/report"
+	"moonshot/services"
+	"moonshot/types"
+)
+
+// runReport renders a backtest or the trade ledger's performance as HTML and Markdown reports
+func runReport(args []string) {
+	if len(args) == 0 {
+		fatal("Missing report command", fmt.Errorf("usage: report backtest|ledger [flags]"))
+	}
+
+	switch args[0] {
+	case "backtest":
+		runReportBacktest(args[1:])
+	case "ledger":
+		runReportLedger(args[1:])
+	default:
+		fatal("Unknown report command", fmt.Errorf("%q is not backtest or ledger", args[0]))
+	}
+}
+
+// reportFlags registers the output flags shared by the report commands
+func reportFlags(flags *flag.FlagSet) (htmlFile, markdownFile, fngFile *string, refreshFNG *bool) {
+	htmlFile = flags.String("html", "moonshot-report.html", "HTML report file, empty to skip")
+	markdownFile = flags.String("markdown", "moonshot-report.md", "Markdown summary file, empty to skip")
+	fngFile = flags.String("fng-history", fngHistoryPath, "Fear & Greed history CSV, fetched when missing")
+	refreshFNG = flags.Bool("refresh-fng", false, "fetch the Fear & Greed history even if the file exists")
+	return htmlFile, markdownFile, fngFile, refreshFNG
+}
+
+// runReportBacktest backtests the live configuration, or the parameters in a JSON file, and reports it
+func runReportBacktest(args []string) {
+	flags := flag.NewFlagSet("report backtest", flag.ExitOnError)
+	paramsFile := flags.String("params", "", "JSON file with the parameters to backtest, e.g. one of a sweep's results (default live configuration)")
+	since := flags.String("since", "", "first day to backtest (YYYY-MM-DD, default start of the data)")
+	until := flags.String("until", "", "day to stop before (YYYY-MM-DD, default end of the data)")
+	htmlFile, markdownFile, fngFile, refreshFNG := reportFlags(flags)
+	flags.Parse(args)
+
+	params := loadBacktestDefaults()
+	if *paramsFile != "" {
+		file, err := os.Open(*paramsFile)
+		if err != nil {
+			fatal("Failed to open parameters", err)
+		}
+		err = json.NewDecoder(file).Decode(&params)
+		file.Close()
+		if err != nil {
+			fatal("Invalid parameters", err)
+		}
+	}
+
+	data, err := loadBacktestData(context.Background(), params.Assets(), *fngFile, *refreshFNG)
+	if err != nil {
+		fatal("Failed to load backtest data", err)
+	}
+	window, err := backtestWindow(data, *since, *until)
+	if err != nil {
+		fatal("Invalid backtest range", err)
+	}
+
+	result, err := backtest.Run(data, params, window.Start, window.End)
+	if err != nil {
+		fatal("Backtest failed", err)
+	}
+
+	writeReports(report.FromBacktest(result), *htmlFile, *markdownFile)
+}
+
+// runReportLedger reports the trade ledger's performance, valued at daily closes
+func runReportLedger(args []string) {
+	flags := flag.NewFlagSet("report ledger", flag.ExitOnError)
+	offline := flags.Bool("offline", false, "use only stored candles instead of fetching missing ones from Coinbase")
+	htmlFile, markdownFile, fngFile, refreshFNG := reportFlags(flags)
+	flags.Parse(args)
+
+	if !*offline {
+		setupCoinbase()
+	}
+
+	ctx := context.Background()
+	entries, err := ledger.Entries()
+	if err != nil {
+		fatal("Failed to read ledger", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No trades recorded in the ledger yet")
+		return
+	}
+
+	start := entries[0].Timestamp
+	for _, entry := range entries {
+		if entry.Timestamp.Before(start) {
+			start = entry.Timestamp
+		}
+	}
+	start = start.UTC().Truncate(24 * time.Hour)
+	now := time.Now()
+
+	candles := make(map[string][]types.Candle)
+	for _, entry := range entries {
+		if _, ok := candles[entry.Asset]; ok {
+			continue
+		}
+		productID := entry.Asset + "-USDC"
+		var series []types.Candle
+		if *offline {
+			series, err = candleStore.Candles(productID, services.GranularityOneDay, start, now)
+		} else {
+			series, err = candleHistory.Candles(ctx, productID, services.GranularityOneDay, start, now)
+		}
+		if err != nil {
+			fatal("Failed to load candles", err)
+		}
+		candles[entry.Asset] = series
+	}
+
+	history, err := loadFNGHistory(ctx, *fngFile, *refreshFNG)
+	if err != nil {
+		slog.Warn("Fear & Greed history unavailable, leaving it off the chart", "error", err)
+	}
+
+	perf := report.Performance(entries, candles, now)
+	writeReports(report.FromLedger(entries, perf, candles, history), *htmlFile, *markdownFile)
+}
+
+// writeReports writes the report as HTML and Markdown to the files that are set
+func writeReports(r *report.Report, htmlFile, markdownFile string) {
+	if htmlFile == "" && markdownFile == "" {
+		fatal("No report output", fmt.Errorf("set -html or -markdown"))
+	}
+
+	outputs := []struct {
+		path  string
+		write func(*os.File) error
+	}{
+		{htmlFile, func(file *os.File) error { return report.WriteHTML(file, r) }},
+		{markdownFile, func(file *os.File) error { return report.WriteMarkdown(file, r) }},
+	}
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+		file, err := os.Create(output.path)
+		if err != nil {
+			fatal("Failed to create report file", err)
+		}
+		err = output.write(file)
+		file.Close()
+		if err != nil {
+			fatal("Failed to write report", err)
+		}
+		slog.Info("Report written", "output", output.path)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"moonshot/backtest"
+	"moonshot/services"
+	"moonshot/store"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// sweepOutput is the JSON document written by the sweep command
+type sweepOutput struct {
+	Metric      string             `json:"metric"`
+	Results     []*backtest.Result `json:"results"`
+	WalkForward []backtest.Fold    `json:"walk_forward,omitempty"`
+	Skipped     []backtest.Skipped `json:"skipped,omitempty"`
+}
+
+// defaultBacktestParams returns the backtest parameters matching the live bot configuration
+func defaultBacktestParams(config *types.BotConfig) backtest.Params {
+	return backtest.Params{
+		Frequency:       strings.ToLower(config.InvestmentFrequency),
+		BaseInvestment:  config.WeeklyBaseInvestment,
+		MultiplierCurve: backtest.CurveFNG,
+		MinMultiplier:   config.MinMultiplier,
+		MaxMultiplier:   config.MaxMultiplier,
+		BufferCurve:     backtest.BufferDynamic,
+		MaxBuffer:       decimal.NewFromInt(20),
+		Allocations: map[string]decimal.Decimal{
+			"BTC": config.BTCAllocation,
+			"ETH": config.ETHAllocation,
+		},
+	}
+}
+
+// runSweep backtests every combination of a parameter grid and writes the results ranked by a metric
+func runSweep(args []string) {
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	gridFile := flags.String("grid", "", "JSON file listing the values to sweep for each parameter")
+	since := flags.String("since", "", "first day to backtest (YYYY-MM-DD, default start of the data)")
+	until := flags.String("until", "", "day to stop before (YYYY-MM-DD, default end of the data)")
+	metric := flags.String("rank", backtest.MetricIRR, "metric to rank by: final_value, irr, max_drawdown or cost_vs_dca")
+	folds := flags.Int("walk-forward", 0, "number of walk-forward folds, 0 = off")
+	workers := flags.Int("workers", 0, "backtests run in parallel (default all CPU cores)")
+	format := flags.String("format", "csv", "output format: csv or json")
+	output := flags.String("output", "", "output file (default stdout)")
+	fngFile := flags.String("fng-history", fngHistoryPath, "Fear & Greed history CSV, fetched when missing")
+	refreshFNG := flags.Bool("refresh-fng", false, "fetch the Fear & Greed history even if the file exists")
+	flags.Parse(args)
+
+	if *gridFile == "" {
+		fatal("Missing -grid file", fmt.Errorf("-grid is required"))
+	}
+	if *format != "csv" && *format != "json" {
+		fatal("Invalid output format", fmt.Errorf("unsupported format: %s (must be csv or json)", *format))
+	}
+	if *folds > 0 && *format == "csv" && *output == "" {
+		fatal("Missing -output file", fmt.Errorf("walk-forward CSV results need -output to name the folds file after"))
+	}
+
+	file, err := os.Open(*gridFile)
+	if err != nil {
+		fatal("Failed to open parameter grid", err)
+	}
+	grid, err := backtest.ReadGrid(file)
+	file.Close()
+	if err != nil {
+		fatal("Invalid parameter grid", err)
+	}
+	params, err := grid.Params(loadBacktestDefaults())
+	if err != nil {
+		fatal("Invalid parameter grid", err)
+	}
+
+	ctx := context.Background()
+	var assets []string
+	for _, p := range params {
+		assets = append(assets, p.Assets()...)
+	}
+	data, err := loadBacktestData(ctx, assets, *fngFile, *refreshFNG)
+	if err != nil {
+		fatal("Failed to load backtest data", err)
+	}
+
+	window, err := backtestWindow(data, *since, *until)
+	if err != nil {
+		fatal("Invalid backtest range", err)
+	}
+	windows := []backtest.Window{window}
+	if *folds > 0 {
+		split := backtest.Windows(window.Start, window.End, *folds+1)
+		if split == nil {
+			fatal("Invalid walk-forward folds", fmt.Errorf("%d folds don't fit in %s", *folds, window))
+		}
+		windows = append(windows, split...)
+	}
+
+	slog.Info("Running parameter sweep",
+		"configurations", len(params), "windows", len(windows), "range", window.String())
+	started := time.Now()
+	results, skipped, err := backtest.Sweep(ctx, data, params, windows, *workers)
+	if err != nil {
+		fatal("Sweep failed", err)
+	}
+	for _, s := range skipped {
+		slog.Warn("Skipped failed backtest", "name", s.Name, "window", s.Window.String(), "error", s.Error)
+	}
+	slog.Info("Sweep completed",
+		"backtests", len(params)*len(windows)-len(skipped),
+		"skipped", len(skipped),
+		"duration", time.Since(started).Round(time.Millisecond).String())
+
+	// The first window is the whole range, the rest are the walk-forward folds
+	ranked, err := backtest.Rank(results[0], *metric)
+	if err != nil {
+		fatal("Invalid ranking metric", err)
+	}
+	if len(ranked) == 0 {
+		fatal("Sweep failed", fmt.Errorf("every backtest over %s failed", window))
+	}
+	walkForward, err := backtest.WalkForward(results[1:], *metric)
+	if err != nil {
+		fatal("Walk-forward failed", err)
+	}
+
+	var writer io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fatal("Failed to create output file", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(sweepOutput{Metric: *metric, Results: ranked, WalkForward: walkForward, Skipped: skipped})
+	} else {
+		err = backtest.WriteCSV(writer, ranked)
+		if err == nil && len(walkForward) > 0 {
+			err = writeFoldsFile(strings.TrimSuffix(*output, ".csv")+"_walk_forward.csv", walkForward, *metric)
+		}
+	}
+	if err != nil {
+		fatal("Failed to write sweep results", err)
+	}
+
+	slog.Info("Best configuration",
+		"name", ranked[0].Name,
+		"final_value", ranked[0].FinalValue.StringFixed(2),
+		"irr", ranked[0].IRR.String(),
+		"max_drawdown", ranked[0].MaxDrawdown.String(),
+		"cost_vs_dca", ranked[0].CostVsDCA.String())
+	if *output != "" {
+		slog.Info("Sweep results written", "output", *output)
+	}
+}
+
+// writeFoldsFile writes the walk-forward folds as CSV to path
+func writeFoldsFile(path string, folds []backtest.Fold, metric string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create walk-forward file: %w", err)
+	}
+	defer file.Close()
+
+	if err := backtest.WriteFoldsCSV(file, folds, metric); err != nil {
+		return err
+	}
+	slog.Info("Walk-forward folds written", "output", path)
+	return nil
+}
+
+// loadBacktestData reads the stored daily candles of the assets and the Fear & Greed history,
+// fetching the history into fngFile if it is missing or refresh is set
+func loadBacktestData(ctx context.Context, assets []string, fngFile string, refresh bool) (*backtest.Data, error) {
+	candles := make(map[string][]types.Candle)
+	for _, asset := range assets {
+		if _, ok := candles[asset]; ok {
+			continue
+		}
+		productID := asset + "-USDC"
+		series, err := candleStore.Candles(productID, services.GranularityOneDay, time.Time{}, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if len(series) == 0 {
+			return nil, fmt.Errorf("no daily candles stored for %s; run candles fetch or candles import first", productID)
+		}
+		candles[asset] = series
+	}
+
+	history, err := loadFNGHistory(ctx, fngFile, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return backtest.NewData(candles, history), nil
+}
+
+// loadFNGHistory reads the Fear & Greed history from path, fetching and saving the whole history
+// first if the file is missing or refresh is set
+func loadFNGHistory(ctx context.Context, path string, refresh bool) ([]types.FearGreedIndex, error) {
+	if !refresh {
+		file, err := os.Open(path)
+		if err == nil {
+			defer file.Close()
+			return store.ReadFNGCSV(file)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to open FNG history: %w", err)
+		}
+	}
+
+	history, err := fngService.GetFearGreedHistory(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FNG history: %w", err)
+	}
+	defer file.Close()
+	if err := store.WriteFNGCSV(file, history); err != nil {
+		return nil, err
+	}
+	slog.Info("Fear & Greed history fetched", "days", len(history), "output", path)
+	return history, nil
+}
+
+// backtestWindow returns the range to backtest: the data's range, narrowed by the optional dates
+func backtestWindow(data *backtest.Data, since, until string) (backtest.Window, error) {
+	start, end := data.Range()
+	if since != "" {
+		t, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			return backtest.Window{}, fmt.Errorf("invalid -since date: %w", err)
+		}
+		if t.After(start) {
+			start = t
+		}
+	}
+	if until != "" {
+		t, err := time.Parse("2006-01-02", until)
+		if err != nil {
+			return backtest.Window{}, fmt.Errorf("invalid -until date: %w", err)
+		}
+		if t.Before(end) {
+			end = t
+		}
+	}
+	if !start.Before(end) {
+		return backtest.Window{}, fmt.Errorf("no prices and Fear & Greed values overlap between %s and %s",
+			start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	return backtest.Window{Start: start, End: end}, nil
+}
@@ -0,0 +1,188 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Indicator names used as keys of the computed values
+const (
+	RSI14              = "rsi_14"
+	SMA50              = "sma_50"
+	SMA200             = "sma_200"
+	EMA21              = "ema_21"
+	MayerMultiple      = "mayer_multiple"
+	ATH                = "ath"
+	ATHDistancePercent = "ath_distance_percent"
+	RealizedVolatility = "realized_volatility"
+)
+
+// ErrInsufficientData is returned when there are fewer values than the indicator period needs
+var ErrInsufficientData = errors.New("insufficient data for indicator")
+
+var hundred = decimal.NewFromInt(100)
+
+// Closes returns the close prices of the candles
+func Closes(candles []types.Candle) []decimal.Decimal {
+	closes := make([]decimal.Decimal, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.Close
+	}
+	return closes
+}
+
+// SMA returns the simple moving average of the last period values
+func SMA(values []decimal.Decimal, period int) (decimal.Decimal, error) {
+	if period <= 0 || len(values) < period {
+		return decimal.Zero, ErrInsufficientData
+	}
+
+	sum := decimal.Zero
+	for _, value := range values[len(values)-period:] {
+		sum = sum.Add(value)
+	}
+	return sum.Div(decimal.NewFromInt(int64(period))), nil
+}
+
+// EMA returns the exponential moving average, seeded with the SMA of the first period values
+func EMA(values []decimal.Decimal, period int) (decimal.Decimal, error) {
+	seed, err := SMA(values[:min(period, len(values))], period)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	k := decimal.NewFromInt(2).Div(decimal.NewFromInt(int64(period + 1)))
+	ema := seed
+	for _, value := range values[period:] {
+		ema = value.Sub(ema).Mul(k).Add(ema)
+	}
+	return ema, nil
+}
+
+// RSI returns the relative strength index using Wilder's smoothing. It needs period+1 values.
+func RSI(values []decimal.Decimal, period int) (decimal.Decimal, error) {
+	if period <= 0 || len(values) < period+1 {
+		return decimal.Zero, ErrInsufficientData
+	}
+
+	n := decimal.NewFromInt(int64(period))
+	gain, loss := decimal.Zero, decimal.Zero
+	for i := 1; i <= period; i++ {
+		change := values[i].Sub(values[i-1])
+		if change.IsPositive() {
+			gain = gain.Add(change)
+		} else {
+			loss = loss.Sub(change)
+		}
+	}
+	gain, loss = gain.Div(n), loss.Div(n)
+
+	for i := period + 1; i < len(values); i++ {
+		change := values[i].Sub(values[i-1])
+		up, down := decimal.Zero, decimal.Zero
+		if change.IsPositive() {
+			up = change
+		} else {
+			down = change.Neg()
+		}
+		gain = gain.Mul(n.Sub(decimal.NewFromInt(1))).Add(up).Div(n)
+		loss = loss.Mul(n.Sub(decimal.NewFromInt(1))).Add(down).Div(n)
+	}
+
+	if loss.IsZero() {
+		return hundred, nil
+	}
+	rs := gain.Div(loss)
+	return hundred.Sub(hundred.Div(decimal.NewFromInt(1).Add(rs))), nil
+}
+
+// Mayer returns the Mayer multiple: the price divided by the 200-day simple moving average of daily closes
+func Mayer(price decimal.Decimal, dailyCloses []decimal.Decimal) (decimal.Decimal, error) {
+	sma, err := SMA(dailyCloses, 200)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !sma.IsPositive() {
+		return decimal.Zero, ErrInsufficientData
+	}
+	return price.Div(sma), nil
+}
+
+// AllTimeHigh returns the highest price seen in the candles or the current price, whichever is higher
+func AllTimeHigh(price decimal.Decimal, candles []types.Candle) decimal.Decimal {
+	high := price
+	for _, candle := range candles {
+		high = decimal.Max(high, candle.High)
+	}
+	return high
+}
+
+// ATHDistance returns how far the price is below the all-time high, in percent
+func ATHDistance(price decimal.Decimal, candles []types.Candle) (decimal.Decimal, error) {
+	high := AllTimeHigh(price, candles)
+	if !high.IsPositive() {
+		return decimal.Zero, ErrInsufficientData
+	}
+	return high.Sub(price).Div(high).Mul(hundred), nil
+}
+
+// Volatility returns the annualized realized volatility in percent: the standard deviation of the
+// last period daily log returns scaled by the square root of 365. It needs period+1 daily closes.
+func Volatility(dailyCloses []decimal.Decimal, period int) (decimal.Decimal, error) {
+	if period < 2 || len(dailyCloses) < period+1 {
+		return decimal.Zero, ErrInsufficientData
+	}
+
+	closes := dailyCloses[len(dailyCloses)-period-1:]
+	returns := make([]float64, 0, period)
+	mean := 0.0
+	for i := 1; i < len(closes); i++ {
+		if !closes[i-1].IsPositive() || !closes[i].IsPositive() {
+			return decimal.Zero, ErrInsufficientData
+		}
+		r := math.Log(closes[i].Div(closes[i-1]).InexactFloat64())
+		returns = append(returns, r)
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	return decimal.NewFromFloat(math.Sqrt(variance*365) * 100), nil
+}
+
+// Compute returns every indicator the daily candles have enough history for, keyed by indicator name
+func Compute(price decimal.Decimal, dailyCandles []types.Candle) map[string]decimal.Decimal {
+	closes := Closes(dailyCandles)
+	values := make(map[string]decimal.Decimal)
+
+	if value, err := RSI(closes, 14); err == nil {
+		values[RSI14] = value
+	}
+	if value, err := SMA(closes, 50); err == nil {
+		values[SMA50] = value
+	}
+	if value, err := SMA(closes, 200); err == nil {
+		values[SMA200] = value
+	}
+	if value, err := EMA(closes, 21); err == nil {
+		values[EMA21] = value
+	}
+	if value, err := Mayer(price, closes); err == nil {
+		values[MayerMultiple] = value
+	}
+	if value, err := ATHDistance(price, dailyCandles); err == nil {
+		values[ATH] = AllTimeHigh(price, dailyCandles)
+		values[ATHDistancePercent] = value
+	}
+
+	return values
+}
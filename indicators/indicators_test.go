@@ -0,0 +1,133 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// series converts integers to decimals
+func series(values ...int64) []decimal.Decimal {
+	decimals := make([]decimal.Decimal, len(values))
+	for i, value := range values {
+		decimals[i] = decimal.NewFromInt(value)
+	}
+	return decimals
+}
+
+func TestEMA(t *testing.T) {
+	// The first period values seed the EMA with their SMA: (1+2+3)/3 = 2. With k = 2/(3+1) = 0.5,
+	// the following values give 2*0.5 + 4*0.5 = 3 and 3*0.5 + 5*0.5 = 4.
+	if got, err := EMA(series(1, 2, 3), 3); err != nil || !got.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("EMA of the seed = %s, %v, want 2", got, err)
+	}
+	if got, err := EMA(series(1, 2, 3, 4, 5), 3); err != nil || !got.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("EMA = %s, %v, want 4", got, err)
+	}
+	if got, err := EMA(series(7, 7, 7, 7, 7, 7), 2); err != nil || !got.Equal(decimal.NewFromInt(7)) {
+		t.Errorf("EMA of a constant series = %s, %v, want 7", got, err)
+	}
+
+	if _, err := EMA(series(1, 2), 3); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("EMA with too few values: err = %v, want ErrInsufficientData", err)
+	}
+	if _, err := EMA(series(1, 2), 0); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("EMA with a zero period: err = %v, want ErrInsufficientData", err)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	tests := []struct {
+		values []decimal.Decimal
+		period int
+		want   string
+	}{
+		{series(1, 2, 3, 4), 3, "100"},     // Only gains
+		{series(4, 3, 2, 1), 3, "0"},       // Only losses
+		{series(5, 5, 5), 2, "100"},        // Flat
+		{series(1, 2, 1), 2, "50"},         // One gain and one loss in the seed
+		{series(1, 2, 1, 2, 1), 2, "37.5"}, // Wilder smoothing: average gain 0.375, loss 0.625
+	}
+	for _, tt := range tests {
+		got, err := RSI(tt.values, tt.period)
+		if err != nil {
+			t.Errorf("RSI(%v, %d): %v", tt.values, tt.period, err)
+			continue
+		}
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("RSI(%v, %d) = %s, want %s", tt.values, tt.period, got, tt.want)
+		}
+	}
+
+	// RSI needs period+1 values for period changes
+	if _, err := RSI(series(1, 2, 3), 3); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("RSI with period values: err = %v, want ErrInsufficientData", err)
+	}
+}
+
+func TestMayer(t *testing.T) {
+	closes := make([]decimal.Decimal, 250)
+	for i := range closes {
+		closes[i] = decimal.NewFromInt(100)
+	}
+	// Only the last 200 closes count
+	closes[0] = decimal.NewFromInt(1_000_000)
+
+	got, err := Mayer(decimal.NewFromInt(150), closes)
+	if err != nil || !got.Equal(decimal.RequireFromString("1.5")) {
+		t.Errorf("Mayer = %s, %v, want 1.5", got, err)
+	}
+	if _, err := Mayer(decimal.NewFromInt(150), closes[:199]); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("Mayer with 199 closes: err = %v, want ErrInsufficientData", err)
+	}
+}
+
+func TestATHDistance(t *testing.T) {
+	candles := []types.Candle{
+		{High: decimal.NewFromInt(80)},
+		{High: decimal.NewFromInt(200)},
+		{High: decimal.NewFromInt(120)},
+	}
+
+	if got, err := ATHDistance(decimal.NewFromInt(150), candles); err != nil || !got.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("ATHDistance = %s, %v, want 25", got, err)
+	}
+	// A price above every candle is the new high
+	if got, err := ATHDistance(decimal.NewFromInt(250), candles); err != nil || !got.IsZero() {
+		t.Errorf("ATHDistance at a new high = %s, %v, want 0", got, err)
+	}
+	if _, err := ATHDistance(decimal.Zero, nil); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("ATHDistance without prices: err = %v, want ErrInsufficientData", err)
+	}
+}
+
+func TestVolatility(t *testing.T) {
+	// Doubling then halving gives daily log returns of +ln 2 and -ln 2: a sample standard deviation
+	// of sqrt(2) * ln 2, annualized by sqrt(365)
+	want := math.Sqrt(2*365) * math.Ln2 * 100
+	got, err := Volatility(series(100, 200, 100), 2)
+	if err != nil || math.Abs(got.InexactFloat64()-want) > 1e-6 {
+		t.Errorf("Volatility = %s, %v, want %f", got, err, want)
+	}
+	// Only the last period returns count
+	got, err = Volatility(series(1, 100, 200, 100), 2)
+	if err != nil || math.Abs(got.InexactFloat64()-want) > 1e-6 {
+		t.Errorf("Volatility of the last returns = %s, %v, want %f", got, err, want)
+	}
+	if got, err := Volatility(series(100, 100, 100, 100), 3); err != nil || !got.IsZero() {
+		t.Errorf("Volatility of constant prices = %s, %v, want 0", got, err)
+	}
+
+	for _, closes := range [][]decimal.Decimal{series(100, 200), series(100, 0, 100)} {
+		if _, err := Volatility(closes, 2); !errors.Is(err, ErrInsufficientData) {
+			t.Errorf("Volatility(%v, 2): err = %v, want ErrInsufficientData", closes, err)
+		}
+	}
+	if _, err := Volatility(series(100, 200, 100), 1); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("Volatility with a period of 1: err = %v, want ErrInsufficientData", err)
+	}
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"moonshot/types"
+)
+
+// redacted replaces the value of any attribute that looks like a secret
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute key fragments whose values must never be logged
+var secretKeys = []string{"secret", "password", "passphrase", "token", "private", "api_key", "apikey", "credential", "webhook"}
+
+// Setup configures the default slog logger from the logging configuration.
+// The returned closer releases the log file, if one was opened.
+func Setup(config *types.LoggingConfig) (io.Closer, error) {
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var output io.Writer = os.Stdout
+	var closer io.Closer = nopCloser{}
+	switch strings.ToLower(config.Output) {
+	case "", "stdout":
+	case "file":
+		if config.FilePath == "" {
+			return nil, fmt.Errorf("log file path is required when logging to a file")
+		}
+		if dir := filepath.Dir(config.FilePath); dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, fmt.Errorf("failed to create log directory: %w", err)
+			}
+		}
+		file, err := os.OpenFile(config.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		output, closer = file, file
+	default:
+		return nil, fmt.Errorf("unsupported log output: %s", config.Output)
+	}
+
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactSecrets,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(output, options)
+	case "json":
+		handler = slog.NewJSONHandler(output, options)
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", config.Format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return closer, nil
+}
+
+// nopCloser is returned when logging to stdout, which must not be closed
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// parseLevel converts a level name to a slog level
+func parseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unsupported log level: %s", level)
+	}
+}
+
+// redactSecrets masks attributes whose key suggests they hold a credential
+func redactSecrets(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	for _, fragment := range secretKeys {
+		if strings.Contains(key, fragment) {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+	return attr
+}
@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"moonshot/types"
+)
+
+// DefaultEMFNamespace is the CloudWatch namespace used when none is configured
+const DefaultEMFNamespace = "Moonshot"
+
+// CloudWatch units used by the emitted metrics
+const (
+	unitCount = "Count"
+	unitNone  = "None"
+	unitPct   = "Percent"
+)
+
+// emfMetric describes a metric in the EMF metadata
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfDirective tells CloudWatch which fields of the log line are metrics and dimensions
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+// emfMetadata is the _aws member of an EMF log line
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// assetTotals accumulates the per-asset metrics of a run
+type assetTotals struct {
+	invested       float64
+	sold           float64
+	ordersPlaced   int
+	ordersFailed   int
+	portfolioValue float64
+}
+
+// EMFWriter writes execution metrics as CloudWatch Embedded Metric Format JSON lines.
+// On Lambda, lines written to stdout are turned into metrics by CloudWatch Logs.
+type EMFWriter struct {
+	writer    io.Writer
+	namespace string
+}
+
+// NewEMFWriter creates an EMF writer emitting metrics under the given namespace
+func NewEMFWriter(writer io.Writer, namespace string) *EMFWriter {
+	if namespace == "" {
+		namespace = DefaultEMFNamespace
+	}
+	return &EMFWriter{
+		writer:    writer,
+		namespace: namespace,
+	}
+}
+
+// WriteRun emits one line with the run totals and one line per asset with the Asset dimension.
+// Only DCA runs report RunSucceeded, RunFailed and the market and portfolio state; dip checks and
+// TWAP slices only report their orders, and nothing when they failed before producing a result.
+func (e *EMFWriter) WriteRun(kind types.RunKind, result *types.ExecutionResult, err error) error {
+	isDCA := kind == types.RunKindDCA
+	if !isDCA && result == nil {
+		return nil
+	}
+
+	timestamp := time.Now()
+	if result != nil && !result.Timestamp.IsZero() {
+		timestamp = result.Timestamp
+	}
+
+	// Run-level metrics
+	fields := map[string]interface{}{
+		"Service": "moonshot",
+		"RunKind": string(kind),
+	}
+	var metrics []emfMetric
+
+	if isDCA {
+		succeeded, failed := 0, 1
+		if err == nil && result != nil && result.Success {
+			succeeded, failed = 1, 0
+		}
+		fields["RunSucceeded"] = succeeded
+		fields["RunFailed"] = failed
+		metrics = append(metrics,
+			emfMetric{Name: "RunSucceeded", Unit: unitCount},
+			emfMetric{Name: "RunFailed", Unit: unitCount},
+		)
+	}
+
+	if err != nil {
+		fields["Error"] = err.Error()
+	}
+
+	assets := make(map[string]*assetTotals)
+	assetFor := func(symbol string) *assetTotals {
+		if assets[symbol] == nil {
+			assets[symbol] = &assetTotals{}
+		}
+		return assets[symbol]
+	}
+
+	if result != nil {
+		fields["RunID"] = result.RunID
+
+		placed, orderFailures := 0, 0
+		for _, order := range result.Orders {
+			asset := assetFor(order.Asset)
+			if order.Success {
+				placed++
+				asset.ordersPlaced++
+				if order.Side == "SELL" {
+					asset.sold += order.Amount.InexactFloat64()
+				} else {
+					asset.invested += order.Amount.InexactFloat64()
+				}
+			} else {
+				orderFailures++
+				asset.ordersFailed++
+			}
+		}
+
+		fields["Invested"] = result.TotalInvested.InexactFloat64()
+		fields["Sold"] = result.TotalSold.InexactFloat64()
+		fields["RealizedPnL"] = result.RealizedPnL.InexactFloat64()
+		fields["OrdersPlaced"] = placed
+		fields["OrdersFailed"] = orderFailures
+		fields["FeeSavings"] = result.FeeSavings.InexactFloat64()
+
+		retries := 0
+		for _, count := range result.Retries {
+			retries += count
+		}
+		fields["Retries"] = retries
+
+		metrics = append(metrics,
+			emfMetric{Name: "Invested", Unit: unitNone},
+			emfMetric{Name: "Sold", Unit: unitNone},
+			emfMetric{Name: "RealizedPnL", Unit: unitNone},
+			emfMetric{Name: "OrdersPlaced", Unit: unitCount},
+			emfMetric{Name: "OrdersFailed", Unit: unitCount},
+			emfMetric{Name: "FeeSavings", Unit: unitNone},
+			emfMetric{Name: "Retries", Unit: unitCount},
+		)
+
+		if isDCA {
+			fields["BufferPercent"] = result.BufferPercent.InexactFloat64()
+			metrics = append(metrics, emfMetric{Name: "BufferPercent", Unit: unitPct})
+		}
+
+		if isDCA && result.FNGIndex != nil {
+			fields["FNG"] = result.FNGIndex.Value
+			fields["FNGMultiplier"] = result.FNGIndex.Multiplier.InexactFloat64()
+			metrics = append(metrics,
+				emfMetric{Name: "FNG", Unit: unitNone},
+				emfMetric{Name: "FNGMultiplier", Unit: unitNone},
+			)
+		}
+
+		if isDCA && result.Portfolio != nil {
+			fields["PortfolioValue"] = result.Portfolio.TotalValue.InexactFloat64()
+			metrics = append(metrics, emfMetric{Name: "PortfolioValue", Unit: unitNone})
+
+			for symbol, asset := range result.Portfolio.Assets {
+				assetFor(symbol).portfolioValue = asset.Value.InexactFloat64()
+			}
+		}
+	}
+
+	if err := e.writeLine(timestamp, []string{"Service"}, metrics, fields); err != nil {
+		return err
+	}
+
+	// Per-asset metrics
+	assetMetrics := []emfMetric{
+		{Name: "Invested", Unit: unitNone},
+		{Name: "Sold", Unit: unitNone},
+		{Name: "OrdersPlaced", Unit: unitCount},
+		{Name: "OrdersFailed", Unit: unitCount},
+	}
+	if isDCA {
+		assetMetrics = append(assetMetrics, emfMetric{Name: "PortfolioValue", Unit: unitNone})
+	}
+	symbols := make([]string, 0, len(assets))
+	for symbol := range assets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		asset := assets[symbol]
+		assetFields := map[string]interface{}{
+			"Service":      "moonshot",
+			"Asset":        symbol,
+			"RunKind":      string(kind),
+			"Invested":     asset.invested,
+			"Sold":         asset.sold,
+			"OrdersPlaced": asset.ordersPlaced,
+			"OrdersFailed": asset.ordersFailed,
+		}
+		if isDCA {
+			assetFields["PortfolioValue"] = asset.portfolioValue
+		}
+		if result != nil {
+			assetFields["RunID"] = result.RunID
+		}
+		if err := e.writeLine(timestamp, []string{"Service", "Asset"}, assetMetrics, assetFields); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeLine encodes a single EMF log line
+func (e *EMFWriter) writeLine(timestamp time.Time, dimensions []string, metrics []emfMetric, fields map[string]interface{}) error {
+	fields["_aws"] = emfMetadata{
+		Timestamp: timestamp.UnixMilli(),
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  e.namespace,
+			Dimensions: [][]string{dimensions},
+			Metrics:    metrics,
+		}},
+	}
+
+	line, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to encode EMF metrics: %w", err)
+	}
+
+	if _, err := e.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write EMF metrics: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// decodeLines parses every EMF line written to buf
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line is not JSON: %v: %s", err, scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// directive returns the single CloudWatch metrics directive of a line
+func directive(t *testing.T, line map[string]interface{}) (int64, map[string]interface{}) {
+	t.Helper()
+
+	raw, err := json.Marshal(line["_aws"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata struct {
+		Timestamp         int64                    `json:"Timestamp"`
+		CloudWatchMetrics []map[string]interface{} `json:"CloudWatchMetrics"`
+	}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		t.Fatalf("invalid _aws metadata: %v", err)
+	}
+	if len(metadata.CloudWatchMetrics) != 1 {
+		t.Fatalf("got %d directives, want 1", len(metadata.CloudWatchMetrics))
+	}
+	return metadata.Timestamp, metadata.CloudWatchMetrics[0]
+}
+
+// metricNames returns the names of the metrics declared by a directive
+func metricNames(d map[string]interface{}) []string {
+	var names []string
+	for _, metric := range d["Metrics"].([]interface{}) {
+		names = append(names, metric.(map[string]interface{})["Name"].(string))
+	}
+	return names
+}
+
+func TestWriteRun(t *testing.T) {
+	timestamp := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	result := &types.ExecutionResult{
+		RunID:         "run-1",
+		Success:       true,
+		TotalInvested: decimal.NewFromInt(150),
+		TotalSold:     decimal.NewFromInt(40),
+		RealizedPnL:   decimal.NewFromInt(10),
+		BufferPercent: decimal.NewFromInt(20),
+		FeeSavings:    decimal.NewFromFloat(0.5),
+		Retries:       map[string]int{"place_order": 2, "get_order": 1},
+		FNGIndex:      &types.FearGreedIndex{Value: 25, Multiplier: decimal.NewFromFloat(1.5)},
+		Portfolio: &types.Portfolio{
+			TotalValue: decimal.NewFromInt(1000),
+			Assets: map[string]*types.Asset{
+				"BTC": {Value: decimal.NewFromInt(700)},
+				"ETH": {Value: decimal.NewFromInt(300)},
+			},
+		},
+		Orders: []types.OrderResult{
+			{Asset: "BTC", Side: "BUY", Amount: decimal.NewFromInt(100), Success: true},
+			{Asset: "ETH", Side: "BUY", Amount: decimal.NewFromInt(50), Success: true},
+			{Asset: "ETH", Side: "SELL", Amount: decimal.NewFromInt(40), Success: true},
+			{Asset: "ETH", Side: "BUY", Amount: decimal.NewFromInt(25), Success: false},
+		},
+		Timestamp: timestamp,
+	}
+
+	var buf bytes.Buffer
+	if err := NewEMFWriter(&buf, "").WriteRun(types.RunKindDCA, result, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want a run line and one per asset", len(lines))
+	}
+
+	run := lines[0]
+	ts, d := directive(t, run)
+	if ts != timestamp.UnixMilli() {
+		t.Errorf("timestamp = %d, want %d", ts, timestamp.UnixMilli())
+	}
+	if d["Namespace"] != DefaultEMFNamespace {
+		t.Errorf("namespace = %v, want %s", d["Namespace"], DefaultEMFNamespace)
+	}
+	if got := d["Dimensions"]; !reflect.DeepEqual(got, []interface{}{[]interface{}{"Service"}}) {
+		t.Errorf("dimensions = %v, want [[Service]]", got)
+	}
+	wantMetrics := []string{"RunSucceeded", "RunFailed", "Invested", "Sold", "RealizedPnL", "OrdersPlaced",
+		"OrdersFailed", "FeeSavings", "Retries", "BufferPercent", "FNG", "FNGMultiplier", "PortfolioValue"}
+	if got := metricNames(d); !reflect.DeepEqual(got, wantMetrics) {
+		t.Errorf("metrics = %v, want %v", got, wantMetrics)
+	}
+	// Every declared metric must be present on the line, or CloudWatch drops it
+	for _, name := range wantMetrics {
+		if _, ok := run[name]; !ok {
+			t.Errorf("metric %s declared but missing", name)
+		}
+	}
+
+	wantValues := map[string]interface{}{
+		"Service":        "moonshot",
+		"RunKind":        "dca",
+		"RunID":          "run-1",
+		"RunSucceeded":   1.0,
+		"RunFailed":      0.0,
+		"Invested":       150.0,
+		"Sold":           40.0,
+		"RealizedPnL":    10.0,
+		"OrdersPlaced":   3.0,
+		"OrdersFailed":   1.0,
+		"BufferPercent":  20.0,
+		"FeeSavings":     0.5,
+		"Retries":        3.0,
+		"FNG":            25.0,
+		"FNGMultiplier":  1.5,
+		"PortfolioValue": 1000.0,
+	}
+	for name, want := range wantValues {
+		if run[name] != want {
+			t.Errorf("%s = %v, want %v", name, run[name], want)
+		}
+	}
+
+	wantAssets := []map[string]interface{}{
+		{"Asset": "BTC", "Invested": 100.0, "Sold": 0.0, "OrdersPlaced": 1.0, "OrdersFailed": 0.0, "PortfolioValue": 700.0},
+		{"Asset": "ETH", "Invested": 50.0, "Sold": 40.0, "OrdersPlaced": 2.0, "OrdersFailed": 1.0, "PortfolioValue": 300.0},
+	}
+	for i, want := range wantAssets {
+		line := lines[i+1]
+		_, d := directive(t, line)
+		if got := d["Dimensions"]; !reflect.DeepEqual(got, []interface{}{[]interface{}{"Service", "Asset"}}) {
+			t.Errorf("asset dimensions = %v, want [[Service Asset]]", got)
+		}
+		if line["Service"] != "moonshot" || line["RunID"] != "run-1" {
+			t.Errorf("asset line %d: Service = %v, RunID = %v", i, line["Service"], line["RunID"])
+		}
+		for name, value := range want {
+			if line[name] != value {
+				t.Errorf("asset line %d: %s = %v, want %v", i, name, line[name], value)
+			}
+		}
+	}
+}
+
+func TestWriteRunFailure(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewEMFWriter(&buf, "Custom").WriteRun(types.RunKindDCA, nil, errors.New("coinbase unavailable")); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want only the run line", len(lines))
+	}
+
+	run := lines[0]
+	_, d := directive(t, run)
+	if d["Namespace"] != "Custom" {
+		t.Errorf("namespace = %v, want Custom", d["Namespace"])
+	}
+	if got, want := metricNames(d), []string{"RunSucceeded", "RunFailed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("metrics = %v, want %v", got, want)
+	}
+	if run["RunSucceeded"] != 0.0 || run["RunFailed"] != 1.0 {
+		t.Errorf("RunSucceeded = %v, RunFailed = %v, want 0 and 1", run["RunSucceeded"], run["RunFailed"])
+	}
+	if run["Error"] != "coinbase unavailable" {
+		t.Errorf("Error = %v, want coinbase unavailable", run["Error"])
+	}
+}
+
+func TestWriteRunDipCheck(t *testing.T) {
+	result := &types.ExecutionResult{
+		RunID:         "run-2",
+		Success:       true,
+		TotalInvested: decimal.NewFromInt(30),
+		BufferPercent: decimal.Zero,
+		Orders: []types.OrderResult{
+			{Asset: "BTC", Side: "BUY", Amount: decimal.NewFromInt(30), Success: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewEMFWriter(&buf, "").WriteRun(types.RunKindDipCheck, result, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want a run line and one asset line", len(lines))
+	}
+
+	run := lines[0]
+	_, d := directive(t, run)
+	wantMetrics := []string{"Invested", "Sold", "RealizedPnL", "OrdersPlaced", "OrdersFailed", "FeeSavings", "Retries"}
+	if got := metricNames(d); !reflect.DeepEqual(got, wantMetrics) {
+		t.Errorf("metrics = %v, want %v", got, wantMetrics)
+	}
+	for _, name := range []string{"RunSucceeded", "RunFailed", "BufferPercent"} {
+		if _, ok := run[name]; ok {
+			t.Errorf("%s emitted for a dip check", name)
+		}
+	}
+	if run["RunKind"] != "dip_check" || run["Invested"] != 30.0 {
+		t.Errorf("RunKind = %v, Invested = %v, want dip_check and 30", run["RunKind"], run["Invested"])
+	}
+
+	_, d = directive(t, lines[1])
+	if got, want := metricNames(d), []string{"Invested", "Sold", "OrdersPlaced", "OrdersFailed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("asset metrics = %v, want %v", got, want)
+	}
+
+	// A dip check that failed before producing a result writes nothing
+	buf.Reset()
+	if err := NewEMFWriter(&buf, "").WriteRun(types.RunKindDipCheck, nil, errors.New("state unavailable")); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q for a failed dip check, want nothing", buf.String())
+	}
+}
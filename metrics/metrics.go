@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Run outcomes used as the outcome label of the runs counter
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+	OutcomeError   = "error"
+)
+
+// namespace prefixes every exported metric
+const namespace = "moonshot"
+
+// Metrics holds the Prometheus collectors for the bot
+type Metrics struct {
+	registry       *prometheus.Registry
+	runs           *prometheus.CounterVec
+	orders         *prometheus.CounterVec
+	invested       *prometheus.CounterVec
+	sold           *prometheus.CounterVec
+	feeSavings     *prometheus.CounterVec
+	fngValue       prometheus.Gauge
+	fngMultiplier  prometheus.Gauge
+	bufferPercent  prometheus.Gauge
+	portfolioValue *prometheus.GaugeVec
+	lastRun        prometheus.Gauge
+	apiLatency     *prometheus.HistogramVec
+	apiRetries     *prometheus.CounterVec
+}
+
+// New creates and registers the bot metrics on a dedicated registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_total",
+			Help:      "DCA runs by outcome (success, failed when an order failed, error when the run aborted).",
+		}, []string{"outcome"}),
+		orders: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_total",
+			Help:      "Orders by asset and status (placed or failed).",
+		}, []string{"asset", "status"}),
+		invested: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "invested_usdc_total",
+			Help:      "USDC invested through successfully placed orders, by asset.",
+		}, []string{"asset"}),
+		sold: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sold_usdc_total",
+			Help:      "USDC proceeds of successfully placed take-profit sells, by asset.",
+		}, []string{"asset"}),
+		feeSavings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fee_savings_usdc_total",
+			Help:      "Taker fees avoided by maker limit order fills, in USDC, by asset.",
+		}, []string{"asset"}),
+		fngValue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "fng_value",
+			Help:      "Fear & Greed Index value seen by the last DCA run.",
+		}),
+		fngMultiplier: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "fng_multiplier",
+			Help:      "Investment multiplier derived from the Fear & Greed Index by the last DCA run.",
+		}),
+		bufferPercent: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "buffer_percent",
+			Help:      "Dip buying buffer percentage kept in USDC by the last DCA run.",
+		}),
+		portfolioValue: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "portfolio_value_usdc",
+			Help:      "Portfolio value in USDC by asset as of the last DCA run.",
+		}, []string{"asset"}),
+		lastRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_timestamp_seconds",
+			Help:      "Unix time of the last DCA run.",
+		}),
+		apiLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Latency of exchange and market data API requests by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		apiRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_retries_total",
+			Help:      "Retried API calls by operation.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		m.runs,
+		m.orders,
+		m.invested,
+		m.sold,
+		m.feeSavings,
+		m.fngValue,
+		m.fngMultiplier,
+		m.bufferPercent,
+		m.portfolioValue,
+		m.lastRun,
+		m.apiLatency,
+		m.apiRetries,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveAPILatency records the duration of an API request
+func (m *Metrics) ObserveAPILatency(endpoint string, duration time.Duration) {
+	m.apiLatency.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// ObserveRun records the outcome of a bot run. Orders and retries are counted for every kind of
+// run, but only DCA runs are counted in runs_total and update the run timestamp and the gauges.
+func (m *Metrics) ObserveRun(kind types.RunKind, result *types.ExecutionResult, err error) {
+	isDCA := kind == types.RunKindDCA
+	if isDCA {
+		m.lastRun.SetToCurrentTime()
+	}
+
+	if err != nil || result == nil {
+		if isDCA {
+			m.runs.WithLabelValues(OutcomeError).Inc()
+		}
+		return
+	}
+
+	for operation, retries := range result.Retries {
+		m.apiRetries.WithLabelValues(operation).Add(float64(retries))
+	}
+
+	for _, order := range result.Orders {
+		if !order.Success {
+			m.orders.WithLabelValues(order.Asset, "failed").Inc()
+			continue
+		}
+		m.orders.WithLabelValues(order.Asset, "placed").Inc()
+		if order.Side == "SELL" {
+			m.sold.WithLabelValues(order.Asset).Add(order.Amount.InexactFloat64())
+			continue
+		}
+		m.invested.WithLabelValues(order.Asset).Add(order.Amount.InexactFloat64())
+		if order.FeeSavings.IsPositive() {
+			m.feeSavings.WithLabelValues(order.Asset).Add(order.FeeSavings.InexactFloat64())
+		}
+	}
+
+	// Dip checks and TWAP slices don't compute the buffer, the index or the portfolio
+	if !isDCA {
+		return
+	}
+
+	if result.Success {
+		m.runs.WithLabelValues(OutcomeSuccess).Inc()
+	} else {
+		m.runs.WithLabelValues(OutcomeFailed).Inc()
+	}
+
+	if result.FNGIndex != nil {
+		m.fngValue.Set(float64(result.FNGIndex.Value))
+		m.fngMultiplier.Set(result.FNGIndex.Multiplier.InexactFloat64())
+	}
+	m.bufferPercent.Set(result.BufferPercent.InexactFloat64())
+
+	if result.Portfolio != nil {
+		m.portfolioValue.Reset()
+		m.portfolioValue.WithLabelValues("USDC").Set(result.Portfolio.USDCBalance.Add(result.Portfolio.USDCHold).InexactFloat64())
+		for symbol, asset := range result.Portfolio.Assets {
+			// Leave assets that could not be valued absent rather than reporting zero
+			if asset.Error == "" {
+				m.portfolioValue.WithLabelValues(symbol).Set(asset.Value.InexactFloat64())
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"moonshot/types"
+)
+
+// emailTimeout bounds connecting to the SMTP server and the whole exchange with it, so a hung
+// server can't stall a run
+const emailTimeout = 15 * time.Second
+
+// EmailNotifier sends messages by email over SMTP
+type EmailNotifier struct {
+	server     string
+	port       int
+	username   string
+	password   string
+	from       string
+	recipients []string
+}
+
+// NewEmailNotifier creates a new email notifier
+func NewEmailNotifier(config *types.NotificationConfig) *EmailNotifier {
+	from := config.EmailFrom
+	if from == "" {
+		from = config.SMTPUsername
+	}
+
+	port := config.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+
+	return &EmailNotifier{
+		server:     config.SMTPServer,
+		port:       port,
+		username:   config.SMTPUsername,
+		password:   config.SMTPPassword,
+		from:       from,
+		recipients: config.EmailRecipients,
+	}
+}
+
+// Name returns the channel name
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify emails the message to all recipients
+func (e *EmailNotifier) Notify(event Event, message string) error {
+	// The first line of the rendered message doubles as the subject
+	subject := strings.SplitN(message, "\n", 2)[0]
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(e.recipients, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if e.username != "" {
+		auth = smtp.PlainAuth("", e.username, e.password, e.server)
+	}
+
+	if err := e.send(auth, []byte(body.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// send delivers the message like smtp.SendMail, upgrading to TLS when the server offers it, but
+// within emailTimeout
+func (e *EmailNotifier) send(auth smtp.Auth, msg []byte) error {
+	addr := net.JoinHostPort(e.server, fmt.Sprintf("%d", e.port))
+	conn, err := net.DialTimeout("tcp", addr, emailTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(emailTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, e.server)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.server}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, recipient := range e.recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(msg); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"moonshot/types"
+)
+
+// EventType identifies the kind of notification
+type EventType string
+
+// Supported notification events
+const (
+	EventExecutionSummary EventType = "execution_summary"
+	EventOrderFailed      EventType = "order_failed"
+	EventRunSkipped       EventType = "run_skipped"
+	EventRunFailed        EventType = "run_failed"
+	EventRiskBlocked      EventType = "risk_blocked"
+)
+
+// Event is a notification about something the bot did or refused to do
+type Event struct {
+	Type      EventType                 `json:"type"`
+	Result    *types.ExecutionResult    `json:"result,omitempty"`
+	Decision  *types.InvestmentDecision `json:"decision,omitempty"`
+	Reason    string                    `json:"reason,omitempty"`
+	Error     string                    `json:"error,omitempty"`
+	Timestamp time.Time                 `json:"timestamp"`
+}
+
+// Notifier delivers notifications to a single channel
+type Notifier interface {
+	Name() string
+	Notify(event Event, message string) error
+}
+
+// messageTemplates render the human readable message for each event type
+var messageTemplates = template.Must(template.New("messages").Parse(`
+{{define "execution_summary"}}🌙 Moonshot DCA run completed{{if not .Result.Success}} with errors{{end}}
+Invested: {{.Result.TotalInvested.StringFixed 2}} USDC
+{{- if .Result.TotalSold.IsPositive}}
+Sold: {{.Result.TotalSold.StringFixed 2}} USDC, realized P&L {{.Result.RealizedPnL.StringFixed 2}} USDC
+{{- end}}
+{{- if .Result.FNGIndex}}
+F&G Index: {{.Result.FNGIndex.Value}} ({{.Result.FNGIndex.Classification}}), multiplier {{.Result.FNGIndex.Multiplier.StringFixed 2}}
+{{- end}}
+{{- range .Result.Decisions}}
+• {{.Action}} {{.Asset}}: {{.Amount.StringFixed 2}} USDC @ {{.Price.StringFixed 2}}
+{{- end}}
+{{- if .Result.CatchUp}}{{if .Result.CatchUp.CaughtUpPeriods}}
+Caught up {{len .Result.CatchUp.CaughtUpPeriods}} missed period(s)
+{{- end}}{{end}}
+{{- if .Result.Performance}}
+Portfolio value: {{.Result.Performance.Overall.CurrentValue.StringFixed 2}} USDC, unrealized P&L {{.Result.Performance.Overall.UnrealizedPnL.StringFixed 2}} USDC ({{.Result.Performance.Overall.UnrealizedPnLPercent.StringFixed 2}}%)
+{{- end}}
+{{- if .Result.Error}}
+Last error: {{.Result.Error}}
+{{- end}}{{end}}
+
+{{define "order_failed"}}❌ Moonshot order failed
+{{- if .Decision}}
+{{.Decision.Action}} {{.Decision.Asset}} for {{.Decision.Amount.StringFixed 2}} USDC
+{{- end}}
+Error: {{.Error}}{{end}}
+
+{{define "run_skipped"}}⏭️ Moonshot DCA run skipped
+Reason: {{.Reason}}{{end}}
+
+{{define "run_failed"}}🚨 Moonshot DCA run failed
+Error: {{.Error}}{{end}}
+
+{{define "risk_blocked"}}🛡️ Moonshot risk limit applied
+{{.Reason}}{{end}}
+`))
+
+// Render formats the message for an event using its template
+func Render(event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := messageTemplates.ExecuteTemplate(&buf, string(event.Type), event); err != nil {
+		return "", fmt.Errorf("failed to render %s notification: %w", event.Type, err)
+	}
+	return buf.String(), nil
+}
+
+// MultiNotifier fans notifications out to several channels. Delivery failures are logged and
+// never returned, so notifications can never abort trading.
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+// NewMultiNotifier creates a notifier that delivers to all given channels
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{
+		notifiers: notifiers,
+	}
+}
+
+// NewFromConfig creates a notifier for every channel that is configured
+func NewFromConfig(config *types.NotificationConfig) *MultiNotifier {
+	if config == nil || !config.Enabled {
+		return NewMultiNotifier()
+	}
+
+	var notifiers []Notifier
+	if config.SlackWebhookURL != "" {
+		notifiers = append(notifiers, NewSlackNotifier(config.SlackWebhookURL))
+	}
+	if config.TelegramBotToken != "" && config.TelegramChatID != "" {
+		notifiers = append(notifiers, NewTelegramNotifier(config.TelegramBotToken, config.TelegramChatID))
+	}
+	if config.SMTPServer != "" && len(config.EmailRecipients) > 0 {
+		notifiers = append(notifiers, NewEmailNotifier(config))
+	}
+	if config.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(config.WebhookURL))
+	}
+
+	return NewMultiNotifier(notifiers...)
+}
+
+// Enabled reports whether any channel is configured
+func (m *MultiNotifier) Enabled() bool {
+	return len(m.notifiers) > 0
+}
+
+// Send renders the event and delivers it to every channel
+func (m *MultiNotifier) Send(event Event) {
+	if !m.Enabled() {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	message, err := Render(event)
+	if err != nil {
+		slog.Warn("Failed to render notification", "event", event.Type, "error", err)
+		return
+	}
+
+	for _, n := range m.notifiers {
+		if err := n.Notify(event, message); err != nil {
+			slog.Warn("Failed to send notification", "event", event.Type, "channel", n.Name(), "error", err)
+		}
+	}
+}
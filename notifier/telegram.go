@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// telegramAPIURL is the Telegram Bot API base URL
+const telegramAPIURL = "https://api.telegram.org"
+
+// TelegramNotifier sends messages through a Telegram bot
+type TelegramNotifier struct {
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+// NewTelegramNotifier creates a new Telegram notifier
+func NewTelegramNotifier(botToken, chatID string) *TelegramNotifier {
+	return &TelegramNotifier{
+		botToken: botToken,
+		chatID:   chatID,
+		client:   newHTTPClient(),
+	}
+}
+
+// Name returns the channel name
+func (t *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Notify sends the message to the configured chat
+func (t *TelegramNotifier) Notify(event Event, message string) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, t.botToken)
+	err := postJSON(t.client, url, map[string]string{
+		"chat_id": t.chatID,
+		"text":    message,
+	})
+	if err != nil {
+		// The URL embeds the bot token, which must never end up in logs
+		return fmt.Errorf("telegram sendMessage failed: %s", strings.ReplaceAll(err.Error(), t.botToken, "***"))
+	}
+	return nil
+}
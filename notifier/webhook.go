@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"net/http"
+)
+
+// WebhookNotifier posts the event as JSON to a generic webhook
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// webhookPayload is the JSON body sent to generic webhooks
+type webhookPayload struct {
+	Event
+	Message string `json:"message"`
+}
+
+// NewWebhookNotifier creates a new generic webhook notifier
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: newHTTPClient(),
+	}
+}
+
+// Name returns the channel name
+func (w *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify posts the event and rendered message to the webhook
+func (w *WebhookNotifier) Notify(event Event, message string) error {
+	return postJSON(w.client, w.url, webhookPayload{Event: event, Message: message})
+}
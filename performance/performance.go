@@ -0,0 +1,291 @@
+package performance
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// OverallAsset is the asset name used for the whole-portfolio performance figures
+const OverallAsset = "TOTAL"
+
+var hundred = decimal.NewFromInt(100)
+
+// cashFlow is a dated investor cash flow; negative values are money put in
+type cashFlow struct {
+	when   time.Time
+	amount float64
+}
+
+// Calculate computes cost basis, P&L and returns per asset and overall from ledger
+// entries and current prices. Cost basis uses the average cost method.
+func Calculate(entries []types.LedgerEntry, prices map[string]decimal.Decimal, asOf time.Time) *types.PerformanceReport {
+	sorted := append([]types.LedgerEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	report := &types.PerformanceReport{
+		Assets: make(map[string]*types.AssetPerformance),
+		AsOf:   asOf,
+	}
+
+	byAsset := make(map[string][]types.LedgerEntry)
+	for _, entry := range sorted {
+		byAsset[entry.Asset] = append(byAsset[entry.Asset], entry)
+	}
+
+	for asset, assetEntries := range byAsset {
+		report.Assets[asset] = calculateAsset(asset, assetEntries, prices[asset], asOf)
+	}
+
+	report.Overall = calculateOverall(sorted, report.Assets, asOf)
+	return report
+}
+
+// calculateAsset computes performance for a single asset's trades
+func calculateAsset(asset string, entries []types.LedgerEntry, price decimal.Decimal, asOf time.Time) *types.AssetPerformance {
+	perf := &types.AssetPerformance{
+		Asset:         asset,
+		Quantity:      decimal.Zero,
+		TotalInvested: decimal.Zero,
+		TotalProceeds: decimal.Zero,
+		CostBasis:     decimal.Zero,
+		RealizedPnL:   decimal.Zero,
+		CurrentPrice:  price,
+		Trades:        len(entries),
+	}
+
+	var flows []cashFlow
+	twr := 1.0
+	startValue := decimal.Zero
+
+	for _, entry := range entries {
+		if perf.FirstTrade.IsZero() {
+			perf.FirstTrade = entry.Timestamp
+		}
+
+		// Time-weighted return: value the holdings at this trade's price before the cash flow
+		if perf.Quantity.GreaterThan(decimal.Zero) {
+			twr *= subPeriodReturn(perf.Quantity, entry.Price, startValue)
+		}
+
+		if isSell(entry) {
+			proceeds := entry.QuoteAmount.Sub(entry.Fee)
+			costOfSold := decimal.Zero
+			if perf.Quantity.GreaterThan(decimal.Zero) {
+				costOfSold = perf.CostBasis.Mul(entry.Quantity).Div(perf.Quantity)
+			}
+			perf.CostBasis = perf.CostBasis.Sub(costOfSold)
+			perf.Quantity = perf.Quantity.Sub(entry.Quantity)
+			perf.TotalProceeds = perf.TotalProceeds.Add(proceeds)
+			perf.RealizedPnL = perf.RealizedPnL.Add(proceeds.Sub(costOfSold))
+			flows = append(flows, cashFlow{when: entry.Timestamp, amount: proceeds.InexactFloat64()})
+		} else {
+			cost := entry.QuoteAmount.Add(entry.Fee)
+			perf.CostBasis = perf.CostBasis.Add(cost)
+			perf.Quantity = perf.Quantity.Add(entry.Quantity)
+			perf.TotalInvested = perf.TotalInvested.Add(cost)
+			flows = append(flows, cashFlow{when: entry.Timestamp, amount: -cost.InexactFloat64()})
+		}
+
+		// The post-trade value at the trade price starts the next sub-period
+		startValue = perf.Quantity.Mul(entry.Price)
+	}
+
+	// Final sub-period runs from the last trade to now at the current price
+	if price.IsZero() && len(entries) > 0 {
+		price = entries[len(entries)-1].Price
+		perf.CurrentPrice = price
+	}
+	if perf.Quantity.GreaterThan(decimal.Zero) {
+		twr *= subPeriodReturn(perf.Quantity, price, startValue)
+	}
+
+	perf.CurrentValue = perf.Quantity.Mul(price)
+	finishAsset(perf, twr, flows, asOf)
+	return perf
+}
+
+// calculateOverall combines all assets into whole-portfolio figures. The time-weighted
+// return values each asset at its most recent trade price, since prices of assets not
+// traded at a given moment are not in the ledger.
+func calculateOverall(entries []types.LedgerEntry, assets map[string]*types.AssetPerformance, asOf time.Time) *types.AssetPerformance {
+	overall := &types.AssetPerformance{
+		Asset:         OverallAsset,
+		Quantity:      decimal.Zero,
+		TotalInvested: decimal.Zero,
+		TotalProceeds: decimal.Zero,
+		CostBasis:     decimal.Zero,
+		CurrentValue:  decimal.Zero,
+		RealizedPnL:   decimal.Zero,
+		Trades:        len(entries),
+	}
+
+	for _, perf := range assets {
+		overall.TotalInvested = overall.TotalInvested.Add(perf.TotalInvested)
+		overall.TotalProceeds = overall.TotalProceeds.Add(perf.TotalProceeds)
+		overall.CostBasis = overall.CostBasis.Add(perf.CostBasis)
+		overall.CurrentValue = overall.CurrentValue.Add(perf.CurrentValue)
+		overall.RealizedPnL = overall.RealizedPnL.Add(perf.RealizedPnL)
+		if overall.FirstTrade.IsZero() || (!perf.FirstTrade.IsZero() && perf.FirstTrade.Before(overall.FirstTrade)) {
+			overall.FirstTrade = perf.FirstTrade
+		}
+	}
+
+	holdings := make(map[string]decimal.Decimal)
+	lastPrices := make(map[string]decimal.Decimal)
+	valueAt := func() decimal.Decimal {
+		total := decimal.Zero
+		for asset, qty := range holdings {
+			total = total.Add(qty.Mul(lastPrices[asset]))
+		}
+		return total
+	}
+
+	var flows []cashFlow
+	twr := 1.0
+	startValue := decimal.Zero
+
+	for _, entry := range entries {
+		lastPrices[entry.Asset] = entry.Price
+		if startValue.GreaterThan(decimal.Zero) {
+			twr *= valueAt().InexactFloat64() / startValue.InexactFloat64()
+		}
+
+		if isSell(entry) {
+			holdings[entry.Asset] = holdings[entry.Asset].Sub(entry.Quantity)
+			flows = append(flows, cashFlow{when: entry.Timestamp, amount: entry.QuoteAmount.Sub(entry.Fee).InexactFloat64()})
+		} else {
+			holdings[entry.Asset] = holdings[entry.Asset].Add(entry.Quantity)
+			flows = append(flows, cashFlow{when: entry.Timestamp, amount: -entry.QuoteAmount.Add(entry.Fee).InexactFloat64()})
+		}
+
+		startValue = valueAt()
+	}
+
+	for asset, perf := range assets {
+		lastPrices[asset] = perf.CurrentPrice
+	}
+	if startValue.GreaterThan(decimal.Zero) {
+		twr *= valueAt().InexactFloat64() / startValue.InexactFloat64()
+	}
+
+	finishAsset(overall, twr, flows, asOf)
+	return overall
+}
+
+// finishAsset fills in the derived P&L and return fields
+func finishAsset(perf *types.AssetPerformance, twr float64, flows []cashFlow, asOf time.Time) {
+	perf.UnrealizedPnL = perf.CurrentValue.Sub(perf.CostBasis)
+	if perf.CostBasis.GreaterThan(decimal.Zero) {
+		perf.UnrealizedPnLPercent = perf.UnrealizedPnL.Div(perf.CostBasis).Mul(hundred).Round(2)
+	}
+	if perf.Quantity.GreaterThan(decimal.Zero) {
+		perf.AverageCost = perf.CostBasis.Div(perf.Quantity).Round(8)
+	}
+
+	if len(flows) > 0 {
+		perf.TimeWeightedReturn = decimal.NewFromFloat(twr - 1).Mul(hundred).Round(2)
+
+		if perf.CurrentValue.GreaterThan(decimal.Zero) {
+			flows = append(flows, cashFlow{when: asOf, amount: perf.CurrentValue.InexactFloat64()})
+		}
+		if rate, ok := xirr(flows); ok {
+			perf.MoneyWeightedReturn = decimal.NewFromFloat(rate).Mul(hundred).Round(2)
+		}
+	}
+}
+
+// subPeriodReturn returns the growth factor of holdings between two valuations
+func subPeriodReturn(quantity, price, startValue decimal.Decimal) float64 {
+	if startValue.LessThanOrEqual(decimal.Zero) {
+		return 1
+	}
+	return quantity.Mul(price).InexactFloat64() / startValue.InexactFloat64()
+}
+
+// isSell reports whether the ledger entry is a sale
+func isSell(entry types.LedgerEntry) bool {
+	return strings.EqualFold(entry.Side, "SELL")
+}
+
+// CashFlow is a dated investor cash flow; negative amounts are money put in
+type CashFlow struct {
+	When   time.Time
+	Amount decimal.Decimal
+}
+
+// IRR returns the annualized money-weighted return of the cash flows in percent. It returns false
+// when no rate can be found.
+func IRR(flows []CashFlow) (decimal.Decimal, bool) {
+	converted := make([]cashFlow, len(flows))
+	for i, flow := range flows {
+		converted[i] = cashFlow{when: flow.When, amount: flow.Amount.InexactFloat64()}
+	}
+	rate, ok := xirr(converted)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return decimal.NewFromFloat(rate).Mul(hundred).Round(2), true
+}
+
+// xirr computes the annualized internal rate of return for irregularly spaced cash
+// flows. It returns false when no rate can be found (e.g. all flows have the same sign).
+func xirr(flows []cashFlow) (float64, bool) {
+	if len(flows) < 2 {
+		return 0, false
+	}
+
+	hasPositive, hasNegative := false, false
+	for _, flow := range flows {
+		hasPositive = hasPositive || flow.amount > 0
+		hasNegative = hasNegative || flow.amount < 0
+	}
+	if !hasPositive || !hasNegative {
+		return 0, false
+	}
+
+	start := flows[0].when
+	for _, flow := range flows {
+		if flow.when.Before(start) {
+			start = flow.when
+		}
+	}
+
+	npv := func(rate float64) float64 {
+		total := 0.0
+		for _, flow := range flows {
+			years := flow.when.Sub(start).Hours() / 24 / 365
+			total += flow.amount / math.Pow(1+rate, years)
+		}
+		return total
+	}
+
+	// Bisection is slower than Newton's method but never diverges
+	low, high := -0.9999, 10.0
+	npvLow, npvHigh := npv(low), npv(high)
+	if math.Signbit(npvLow) == math.Signbit(npvHigh) {
+		return 0, false
+	}
+
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		npvMid := npv(mid)
+		if math.Abs(npvMid) < 1e-7 || (high-low)/2 < 1e-9 {
+			return mid, true
+		}
+		if math.Signbit(npvMid) == math.Signbit(npvLow) {
+			low, npvLow = mid, npvMid
+		} else {
+			high = mid
+		}
+	}
+
+	return (low + high) / 2, true
+}
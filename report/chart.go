@@ -0,0 +1,208 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Chart layout in SVG user units
+const (
+	chartWidth   = 960
+	chartHeight  = 420
+	marginLeft   = 72
+	marginRight  = 48
+	marginTop    = 16
+	marginBottom = 36
+	plotWidth    = chartWidth - marginLeft - marginRight
+	plotHeight   = chartHeight - marginTop - marginBottom
+)
+
+// Chart colors, shared with the legend of the HTML report
+const (
+	colorValue     = "#2563eb"
+	colorInvested  = "#6b7280"
+	colorBenchmark = "#9333ea"
+	colorFNG       = "#f59e0b"
+	colorBuy       = "#16a34a"
+	colorSell      = "#dc2626"
+)
+
+// chart maps times and values onto the plot area
+type chart struct {
+	start, end time.Time
+	maxValue   float64
+}
+
+// x returns the horizontal position of a time
+func (c chart) x(t time.Time) float64 {
+	span := c.end.Sub(c.start).Seconds()
+	if span <= 0 {
+		return marginLeft + plotWidth/2
+	}
+	return marginLeft + plotWidth*t.Sub(c.start).Seconds()/span
+}
+
+// y returns the vertical position of a value on the left axis
+func (c chart) y(value float64) float64 {
+	return marginTop + plotHeight*(1-value/c.maxValue)
+}
+
+// yFNG returns the vertical position of an F&G value on the right axis
+func (c chart) yFNG(value int) float64 {
+	return marginTop + plotHeight*(1-float64(value)/100)
+}
+
+// EquityChart renders the report's points as an SVG chart: the portfolio value, the invested
+// capital and the benchmark on the left axis, the Fear & Greed Index shaded behind them on the
+// right axis, and a marker on the value line for every trade.
+func EquityChart(r *Report) string {
+	if len(r.Points) == 0 {
+		return ""
+	}
+
+	c := chart{start: r.Points[0].Time, end: r.Points[len(r.Points)-1].Time}
+	for _, point := range r.Points {
+		c.maxValue = math.Max(c.maxValue, point.Value.InexactFloat64())
+		c.maxValue = math.Max(c.maxValue, point.Invested.InexactFloat64())
+		if r.BenchmarkLabel != "" {
+			c.maxValue = math.Max(c.maxValue, point.Benchmark.InexactFloat64())
+		}
+	}
+	step := niceStep(c.maxValue / 5)
+	c.maxValue = math.Max(math.Ceil(c.maxValue/step)*step, step)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		chartWidth, chartHeight, html.EscapeString("Equity curve of "+r.Title))
+	b.WriteString(`<style>text{font:11px sans-serif;fill:#4b5563}</style>`)
+
+	// Fear & Greed shading, on its own axis from 0 to 100
+	var fng []string
+	for _, point := range r.Points {
+		if point.HasFNG {
+			fng = append(fng, fmt.Sprintf("%.1f,%.1f", c.x(point.Time), c.yFNG(point.FNG)))
+		}
+	}
+	if len(fng) > 1 {
+		first, last := strings.Split(fng[0], ","), strings.Split(fng[len(fng)-1], ",")
+		fmt.Fprintf(&b, `<polygon points="%s,%.1f %s %s,%.1f" fill="%s" fill-opacity="0.12" stroke="none"/>`,
+			first[0], c.yFNG(0), strings.Join(fng, " "), last[0], c.yFNG(0), colorFNG)
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-opacity="0.5" stroke-width="1"/>`,
+			strings.Join(fng, " "), colorFNG)
+		for _, value := range []int{0, 25, 50, 75, 100} {
+			fmt.Fprintf(&b, `<text x="%d" y="%.1f" dy="4">%d</text>`, chartWidth-marginRight+6, c.yFNG(value), value)
+		}
+	}
+
+	// Value grid and axis labels
+	for value := 0.0; value <= c.maxValue+step/2; value += step {
+		y := c.y(value)
+		fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" stroke="#e5e7eb"/>`, marginLeft, marginLeft+plotWidth, y, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" dy="4" text-anchor="end">%s</text>`, marginLeft-6, y, compactUSD(value))
+	}
+	for _, tick := range timeTicks(c.start, c.end) {
+		x := c.x(tick)
+		fmt.Fprintf(&b, `<line x1="%.1f" x2="%.1f" y1="%d" y2="%d" stroke="#e5e7eb"/>`, x, x, marginTop, marginTop+plotHeight)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, x, chartHeight-12, tickLabel(tick, c.end.Sub(c.start)))
+	}
+
+	// Invested capital steps up at each deposit; the value and benchmark are drawn as lines
+	var invested, value, benchmark []string
+	for i, point := range r.Points {
+		x := c.x(point.Time)
+		if i > 0 {
+			invested = append(invested, fmt.Sprintf("%.1f,%.1f", x, c.y(r.Points[i-1].Invested.InexactFloat64())))
+		}
+		invested = append(invested, fmt.Sprintf("%.1f,%.1f", x, c.y(point.Invested.InexactFloat64())))
+		value = append(value, fmt.Sprintf("%.1f,%.1f", x, c.y(point.Value.InexactFloat64())))
+		benchmark = append(benchmark, fmt.Sprintf("%.1f,%.1f", x, c.y(point.Benchmark.InexactFloat64())))
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, strings.Join(invested, " "), colorInvested)
+	if r.BenchmarkLabel != "" {
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5" stroke-dasharray="6 4"/>`,
+			strings.Join(benchmark, " "), colorBenchmark)
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(value, " "), colorValue)
+
+	// Trades sit on the value line at the last point at or before them
+	for _, marker := range r.Markers {
+		i := sort.Search(len(r.Points), func(i int) bool {
+			return r.Points[i].Time.After(marker.Time)
+		}) - 1
+		if i < 0 {
+			continue
+		}
+		color := colorBuy
+		if strings.EqualFold(marker.Side, "SELL") {
+			color = colorSell
+		}
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s" fill-opacity="0.7"><title>%s</title></circle>`,
+			c.x(marker.Time), c.y(r.Points[i].Value.InexactFloat64()), color, html.EscapeString(markerTitle(marker)))
+	}
+
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#d1d5db"/>`, marginLeft, marginTop, plotWidth, plotHeight)
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// markerTitle describes a trade for the marker's tooltip
+func markerTitle(marker Marker) string {
+	return fmt.Sprintf("%s %s %s %s at %s", marker.Time.Format("2006-01-02"), marker.Side, marker.Asset,
+		usd(marker.Amount), usd(marker.Price))
+}
+
+// niceStep rounds a raw grid step up to 1, 2 or 5 times a power of ten
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, factor := range []float64{1, 2, 5, 10} {
+		if raw <= factor*magnitude {
+			return factor * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// compactUSD formats an axis value, e.g. $1.5k or $2M
+func compactUSD(value float64) string {
+	switch {
+	case value >= 1e6:
+		return "$" + strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value/1e6), "0"), ".") + "M"
+	case value >= 1e3:
+		return "$" + strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value/1e3), "0"), ".") + "k"
+	default:
+		return fmt.Sprintf("$%.0f", value)
+	}
+}
+
+// timeTicks returns about six evenly spaced times from start to end, on day boundaries
+func timeTicks(start, end time.Time) []time.Time {
+	days := int(end.Sub(start).Hours() / 24)
+	if days < 1 {
+		return []time.Time{start}
+	}
+
+	count := 6
+	if days < count {
+		count = days
+	}
+	ticks := make([]time.Time, 0, count+1)
+	for i := 0; i <= count; i++ {
+		ticks = append(ticks, day(start).AddDate(0, 0, days*i/count))
+	}
+	return ticks
+}
+
+// tickLabel formats a time axis label, with days only for ranges under a year
+func tickLabel(t time.Time, span time.Duration) string {
+	if span < 365*24*time.Hour {
+		return t.Format("Jan 2")
+	}
+	return t.Format("Jan 2006")
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// htmlTemplate renders a report as a single HTML page with inline styles and SVG, so it needs no
+// scripts, fonts or other files to display
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Report.Title}}</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif;color:#111827;margin:0 auto;padding:24px;max-width:1000px}
+h1{font-size:22px;margin:0 0 4px}
+h2{font-size:16px;margin:28px 0 8px}
+.subtitle,.generated,.notes{color:#6b7280;font-size:13px}
+.metrics{display:grid;grid-template-columns:repeat(auto-fill,minmax(180px,1fr));gap:8px;margin-top:16px}
+.metric{border:1px solid #e5e7eb;border-radius:6px;padding:8px 10px}
+.metric .label{color:#6b7280;font-size:12px}
+.metric .value{font-size:16px;font-weight:600;margin-top:2px}
+svg{width:100%;height:auto;display:block}
+.legend{font-size:12px;color:#4b5563;margin-top:6px}
+.legend span{display:inline-block;margin-right:14px}
+.swatch{display:inline-block;width:12px;height:3px;margin-right:4px;vertical-align:middle}
+.dot{display:inline-block;width:8px;height:8px;border-radius:50%;margin-right:4px;vertical-align:middle}
+table{border-collapse:collapse;font-size:13px;width:100%}
+th,td{border-bottom:1px solid #e5e7eb;padding:6px 8px;text-align:right}
+th:first-child,td:first-child{text-align:left}
+th{color:#6b7280;font-weight:600}
+</style>
+</head>
+<body>
+<h1>{{.Report.Title}}</h1>
+<div class="subtitle">{{.Report.Subtitle}}</div>
+<div class="metrics">
+{{- range .Report.Metrics}}
+<div class="metric"><div class="label">{{.Label}}</div><div class="value">{{.Value}}</div></div>
+{{- end}}
+</div>
+{{- if .Chart}}
+<h2>Equity curve</h2>
+{{.Chart}}
+<div class="legend">
+<span><i class="swatch" style="background:{{.Colors.Value}}"></i>Portfolio value</span>
+<span><i class="swatch" style="background:{{.Colors.Invested}}"></i>Invested capital</span>
+{{- if .Report.BenchmarkLabel}}
+<span><i class="swatch" style="background:{{.Colors.Benchmark}}"></i>{{.Report.BenchmarkLabel}}</span>
+{{- end}}
+<span><i class="swatch" style="background:{{.Colors.FNG}};opacity:.5"></i>Fear &amp; Greed Index (right axis)</span>
+<span><i class="dot" style="background:{{.Colors.Buy}}"></i>Buy</span>
+<span><i class="dot" style="background:{{.Colors.Sell}}"></i>Sell</span>
+</div>
+{{- end}}
+{{- if .Report.Assets.Rows}}
+<h2>Assets</h2>
+<table>
+<tr>{{range .Report.Assets.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Report.Assets.Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Report.Notes}}
+<ul class="notes">
+{{- range .Report.Notes}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+<div class="generated">Generated {{.Report.Generated.Format "2006-01-02 15:04 MST"}} by Moonshot</div>
+</body>
+</html>
+`))
+
+// chartColors are the series colors shown in the legend
+type chartColors struct {
+	Value, Invested, Benchmark, FNG, Buy, Sell template.CSS
+}
+
+// WriteHTML renders the report as a self-contained HTML page with an embedded SVG equity chart
+func WriteHTML(w io.Writer, r *Report) error {
+	data := struct {
+		Report *Report
+		Chart  template.HTML
+		Colors chartColors
+	}{
+		Report: r,
+		Chart:  template.HTML(EquityChart(r)),
+		Colors: chartColors{colorValue, colorInvested, colorBenchmark, colorFNG, colorBuy, colorSell},
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
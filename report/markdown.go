@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown renders the report's summary as Markdown: the metrics, the assets table, how the
+// portfolio value moved and the notes
+func WriteMarkdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", r.Title, r.Subtitle)
+
+	b.WriteString("## Summary\n\n| Metric | Value |\n| --- | ---: |\n")
+	for _, metric := range r.Metrics {
+		fmt.Fprintf(&b, "| %s | %s |\n", escapeCell(metric.Label), escapeCell(metric.Value))
+	}
+
+	if len(r.Assets.Rows) > 0 {
+		b.WriteString("\n## Assets\n\n")
+		writeMarkdownRow(&b, r.Assets.Columns)
+		separators := make([]string, len(r.Assets.Columns))
+		for i := range separators {
+			separators[i] = "---:"
+		}
+		separators[0] = "---"
+		writeMarkdownRow(&b, separators)
+		for _, row := range r.Assets.Rows {
+			writeMarkdownRow(&b, row)
+		}
+	}
+
+	if len(r.Points) > 0 {
+		first, last := r.Points[0], r.Points[len(r.Points)-1]
+		fmt.Fprintf(&b, "\n## Equity\n\nFrom %s to %s the portfolio value went from %s to %s against %s of invested capital",
+			first.Time.Format("2006-01-02"), last.Time.Format("2006-01-02"), usd(first.Value), usd(last.Value), usd(last.Invested))
+		if r.BenchmarkLabel != "" {
+			fmt.Fprintf(&b, " (%s: %s)", r.BenchmarkLabel, usd(last.Benchmark))
+		}
+		fmt.Fprintf(&b, ", over %d trades.\n", len(r.Markers))
+	}
+
+	if len(r.Notes) > 0 {
+		b.WriteString("\n")
+		for _, note := range r.Notes {
+			fmt.Fprintf(&b, "- %s\n", note)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n_Generated %s by Moonshot_\n", r.Generated.Format("2006-01-02 15:04 MST"))
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write Markdown report: %w", err)
+	}
+	return nil
+}
+
+// writeMarkdownRow writes one row of a Markdown table
+func writeMarkdownRow(b *strings.Builder, cells []string) {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = escapeCell(cell)
+	}
+	fmt.Fprintf(b, "| %s |\n", strings.Join(escaped, " | "))
+}
+
+// escapeCell escapes the pipes that would split a Markdown table cell
+func escapeCell(cell string) string {
+	return strings.ReplaceAll(cell, "|", `\|`)
+}
@@ -0,0 +1,290 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"moonshot/backtest"
+	"moonshot/performance"
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Point is the state of the portfolio charted at one time
+type Point struct {
+	Time      time.Time
+	Value     decimal.Decimal // Assets and cash
+	Invested  decimal.Decimal // Capital put in so far
+	Benchmark decimal.Decimal // Benchmark value, if the report has one
+	FNG       int
+	HasFNG    bool
+}
+
+// Marker is a trade marked on the chart
+type Marker struct {
+	Time   time.Time
+	Asset  string
+	Side   string // BUY or SELL
+	Amount decimal.Decimal
+	Price  decimal.Decimal
+}
+
+// Metric is a labelled figure of the summary
+type Metric struct {
+	Label string
+	Value string
+}
+
+// Table is a table of preformatted cells
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// Report is a backtest or the live ledger prepared for rendering
+type Report struct {
+	Title          string
+	Subtitle       string
+	Generated      time.Time
+	Metrics        []Metric
+	Assets         Table
+	Points         []Point
+	Markers        []Marker
+	BenchmarkLabel string // Empty if there is no benchmark to chart
+	Notes          []string
+}
+
+// FromBacktest prepares a backtest result, charting plain DCA as the benchmark
+func FromBacktest(result *backtest.Result) *Report {
+	report := &Report{
+		Title: "Backtest: " + result.Name,
+		Subtitle: fmt.Sprintf("%s to %s, %d scheduled runs", result.Start.Format("2006-01-02"),
+			result.End.Format("2006-01-02"), result.Periods),
+		Generated:      time.Now(),
+		BenchmarkLabel: "Plain DCA",
+		Metrics: []Metric{
+			{"Contributed", usd(result.Contributed)},
+			{"Invested", usd(result.Invested)},
+			{"Cash held back", usd(result.Cash)},
+			{"Final value", usd(result.FinalValue)},
+			{"IRR", percent(result.IRR)},
+			{"Max drawdown", percent(result.MaxDrawdown)},
+			{"Cost vs plain DCA", percent(result.CostVsDCA)},
+			{"Plain DCA final value", usd(result.DCA.FinalValue)},
+			{"Plain DCA IRR", percent(result.DCA.IRR)},
+			{"Plain DCA max drawdown", percent(result.DCA.MaxDrawdown)},
+		},
+		Assets: Table{Columns: []string{"Asset", "Allocation", "Buys", "Average cost", "Plain DCA average cost"}},
+		Notes: []string{
+			"Buys are made at the day's opening price and fees are not modeled.",
+			"Max drawdown is measured on the unit value, so deposits don't hide losses.",
+		},
+	}
+
+	buys := make(map[string]int)
+	for _, buy := range result.Buys {
+		buys[buy.Asset]++
+		report.Markers = append(report.Markers, Marker{
+			Time:   buy.Time,
+			Asset:  buy.Asset,
+			Side:   "BUY",
+			Amount: buy.Amount,
+			Price:  buy.Price,
+		})
+	}
+	for _, asset := range result.Params.Assets() {
+		report.Assets.Rows = append(report.Assets.Rows, []string{
+			asset,
+			result.Params.Allocations[asset].String() + "%",
+			strconv.Itoa(buys[asset]),
+			optionalUSD(result.AverageCost, asset),
+			optionalUSD(result.DCA.AverageCost, asset),
+		})
+	}
+
+	for _, point := range result.Curve {
+		report.Points = append(report.Points, Point{
+			Time:      point.Time,
+			Value:     point.Value,
+			Invested:  point.Contributed,
+			Benchmark: point.DCAValue,
+			FNG:       point.FNG,
+			HasFNG:    true,
+		})
+	}
+
+	return report
+}
+
+// FromLedger prepares the live ledger's performance, charting its daily value at the candles'
+// closes against the capital invested
+func FromLedger(entries []types.LedgerEntry, perf *types.PerformanceReport, candles map[string][]types.Candle, fng []types.FearGreedIndex) *Report {
+	overall := perf.Overall
+	report := &Report{
+		Title:     "Moonshot Performance",
+		Subtitle:  fmt.Sprintf("%d trades since %s", overall.Trades, overall.FirstTrade.Format("2006-01-02")),
+		Generated: time.Now(),
+		Metrics: []Metric{
+			{"Invested", usd(overall.TotalInvested)},
+			{"Proceeds", usd(overall.TotalProceeds)},
+			{"Current value", usd(overall.CurrentValue)},
+			{"Unrealized P&L", fmt.Sprintf("%s (%s)", usd(overall.UnrealizedPnL), percent(overall.UnrealizedPnLPercent))},
+			{"Realized P&L", usd(overall.RealizedPnL)},
+			{"Time-weighted return", percent(overall.TimeWeightedReturn)},
+			{"XIRR", percent(overall.MoneyWeightedReturn)},
+		},
+		Assets: Table{Columns: []string{"Asset", "Quantity", "Average cost", "Invested", "Value",
+			"Unrealized P&L", "P&L %", "TWR %", "XIRR %"}},
+		Notes: []string{
+			"Values are charted at daily closes; invested capital is buys less sale proceeds, including fees.",
+		},
+	}
+
+	symbols := make([]string, 0, len(perf.Assets))
+	for symbol := range perf.Assets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		report.Assets.Rows = append(report.Assets.Rows, assetRow(perf.Assets[symbol]))
+	}
+	report.Assets.Rows = append(report.Assets.Rows, assetRow(overall))
+
+	sorted := append([]types.LedgerEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	for _, entry := range sorted {
+		report.Markers = append(report.Markers, Marker{
+			Time:   entry.Timestamp,
+			Asset:  entry.Asset,
+			Side:   entry.Side,
+			Amount: entry.QuoteAmount,
+			Price:  entry.Price,
+		})
+	}
+	report.Points = ledgerPoints(sorted, candles, fng, perf.AsOf)
+
+	return report
+}
+
+// assetRow formats an asset's performance as a table row
+func assetRow(perf *types.AssetPerformance) []string {
+	return []string{
+		perf.Asset,
+		perf.Quantity.StringFixed(8),
+		usd(perf.AverageCost),
+		usd(perf.TotalInvested),
+		usd(perf.CurrentValue),
+		usd(perf.UnrealizedPnL),
+		perf.UnrealizedPnLPercent.StringFixed(2),
+		perf.TimeWeightedReturn.StringFixed(2),
+		perf.MoneyWeightedReturn.StringFixed(2),
+	}
+}
+
+// ledgerPoints values the ledger's holdings at each day's close from the first trade through asOf
+func ledgerPoints(entries []types.LedgerEntry, candles map[string][]types.Candle, fng []types.FearGreedIndex, asOf time.Time) []Point {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	closes := make(map[string]map[int64]decimal.Decimal)
+	for asset, series := range candles {
+		closes[asset] = make(map[int64]decimal.Decimal, len(series))
+		for _, candle := range series {
+			closes[asset][day(candle.Start).Unix()] = candle.Close
+		}
+	}
+	fngByDay := make(map[int64]int, len(fng))
+	for _, index := range fng {
+		fngByDay[day(index.Timestamp).Unix()] = index.Value
+	}
+
+	quantities := make(map[string]decimal.Decimal)
+	prices := make(map[string]decimal.Decimal)
+	invested := decimal.Zero
+	next := 0
+
+	var points []Point
+	for d := day(entries[0].Timestamp); !d.After(asOf); d = d.AddDate(0, 0, 1) {
+		end := d.AddDate(0, 0, 1)
+		for ; next < len(entries) && entries[next].Timestamp.Before(end); next++ {
+			entry := entries[next]
+			if strings.EqualFold(entry.Side, "SELL") {
+				quantities[entry.Asset] = quantities[entry.Asset].Sub(entry.Quantity)
+				invested = invested.Sub(entry.QuoteAmount.Sub(entry.Fee))
+			} else {
+				quantities[entry.Asset] = quantities[entry.Asset].Add(entry.Quantity)
+				invested = invested.Add(entry.QuoteAmount.Add(entry.Fee))
+			}
+			// Until a close is known the asset is valued at its fill price
+			if _, ok := prices[entry.Asset]; !ok {
+				prices[entry.Asset] = entry.Price
+			}
+		}
+
+		value := decimal.Zero
+		for asset, quantity := range quantities {
+			if price, ok := closes[asset][d.Unix()]; ok {
+				prices[asset] = price
+			}
+			value = value.Add(quantity.Mul(prices[asset]))
+		}
+
+		fngValue, hasFNG := fngByDay[d.Unix()]
+		points = append(points, Point{
+			Time:     d,
+			Value:    value.Round(2),
+			Invested: invested.Round(2),
+			FNG:      fngValue,
+			HasFNG:   hasFNG,
+		})
+	}
+	return points
+}
+
+// LedgerPrices returns the last close of each asset's candles, to value the ledger at
+func LedgerPrices(candles map[string][]types.Candle) map[string]decimal.Decimal {
+	prices := make(map[string]decimal.Decimal)
+	for asset, series := range candles {
+		if len(series) > 0 {
+			prices[asset] = series[len(series)-1].Close
+		}
+	}
+	return prices
+}
+
+// Performance computes the ledger's performance at the last closes of the candles
+func Performance(entries []types.LedgerEntry, candles map[string][]types.Candle, asOf time.Time) *types.PerformanceReport {
+	return performance.Calculate(entries, LedgerPrices(candles), asOf)
+}
+
+// usd formats an amount in USDC
+func usd(amount decimal.Decimal) string {
+	return "$" + amount.StringFixed(2)
+}
+
+// percent formats a percentage
+func percent(value decimal.Decimal) string {
+	return value.StringFixed(2) + "%"
+}
+
+// optionalUSD formats an amount from the map, or a dash if it is missing
+func optionalUSD(values map[string]decimal.Decimal, key string) string {
+	value, ok := values[key]
+	if !ok {
+		return "-"
+	}
+	return usd(value)
+}
+
+// day truncates a time to its UTC day
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"moonshot/store"
+	"moonshot/types"
+)
+
+// CandleHistory serves price history from a local candle store, fetching only the candles it is
+// missing from Coinbase. Closed candles are persisted so gaps are filled incrementally; the candle
+// still in progress is always fetched and never stored.
+type CandleHistory struct {
+	coinbase *CoinbaseService
+	store    store.CandleStore
+}
+
+// NewCandleHistory creates a candle history backed by a Coinbase service and a candle store
+func NewCandleHistory(coinbase *CoinbaseService, candleStore store.CandleStore) *CandleHistory {
+	return &CandleHistory{
+		coinbase: coinbase,
+		store:    candleStore,
+	}
+}
+
+// Candles returns the candles of a product starting in [start, end), oldest first
+func (h *CandleHistory) Candles(ctx context.Context, productID, granularity string, start, end time.Time) ([]types.Candle, error) {
+	step, err := GranularityDuration(granularity)
+	if err != nil {
+		return nil, err
+	}
+	start = start.Truncate(step)
+	closedEnd := time.Now().Truncate(step)
+	if end.Before(closedEnd) {
+		closedEnd = end
+	}
+
+	candles, err := h.store.Candles(productID, granularity, start, closedEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored candles: %w", err)
+	}
+
+	for _, gap := range fetchSpans(candleGaps(candles, start, closedEnd, step), step) {
+		fetched, err := h.fetch(ctx, productID, granularity, gap[0], gap[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := h.store.Put(productID, granularity, fetched); err != nil {
+			return nil, fmt.Errorf("failed to store candles: %w", err)
+		}
+		slog.Debug("Filled candle gaps",
+			"product_id", productID,
+			"granularity", granularity,
+			"start", gap[0].Format(time.RFC3339),
+			"end", gap[1].Format(time.RFC3339),
+			"candles", len(fetched))
+		candles = store.MergeCandles(candles, fetched)
+	}
+
+	if closedEnd.Before(end) {
+		open, err := h.fetch(ctx, productID, granularity, closedEnd, end)
+		if err != nil {
+			return nil, err
+		}
+		candles = store.MergeCandles(candles, open)
+	}
+
+	return candles, nil
+}
+
+// Fill fetches and stores any closed candles missing between start and end, returning how many
+// candles were added
+func (h *CandleHistory) Fill(ctx context.Context, productID, granularity string, start, end time.Time) (int, error) {
+	step, err := GranularityDuration(granularity)
+	if err != nil {
+		return 0, err
+	}
+	before, err := h.store.Candles(productID, granularity, start.Truncate(step), end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stored candles: %w", err)
+	}
+
+	// Only closed candles are stored, so the in-progress one is fetched but not counted
+	if end.After(time.Now()) {
+		end = time.Now()
+	}
+	if _, err := h.Candles(ctx, productID, granularity, start, end); err != nil {
+		return 0, err
+	}
+
+	after, err := h.store.Candles(productID, granularity, start.Truncate(step), end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stored candles: %w", err)
+	}
+	return len(after) - len(before), nil
+}
+
+// fetch returns the candles Coinbase has starting in [start, end)
+func (h *CandleHistory) fetch(ctx context.Context, productID, granularity string, start, end time.Time) ([]types.Candle, error) {
+	fetched, err := h.coinbase.GetCandleHistory(ctx, productID, start, end, granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := fetched[:0]
+	for _, candle := range fetched {
+		if !candle.Start.Before(start) && candle.Start.Before(end) {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, nil
+}
+
+// candleGaps returns the [start, end) ranges between start and end not covered by the candles,
+// which are ordered by start
+func candleGaps(candles []types.Candle, start, end time.Time, step time.Duration) [][2]time.Time {
+	have := make(map[int64]bool, len(candles))
+	for _, candle := range candles {
+		have[candle.Start.Unix()] = true
+	}
+
+	var gaps [][2]time.Time
+	for t := start; t.Before(end); t = t.Add(step) {
+		if have[t.Unix()] {
+			continue
+		}
+		if n := len(gaps); n > 0 && gaps[n-1][1].Equal(t) {
+			gaps[n-1][1] = t.Add(step)
+			continue
+		}
+		gaps = append(gaps, [2]time.Time{t, t.Add(step)})
+	}
+	return gaps
+}
+
+// fetchSpans merges neighbouring gaps into spans of at most one request's worth of candles, so
+// scattered missing candles, such as periods without trades, cost one request rather than one each.
+// A gap longer than a single request is kept whole and paged by the fetch.
+func fetchSpans(gaps [][2]time.Time, step time.Duration) [][2]time.Time {
+	limit := time.Duration(maxCandlesPerRequest) * step
+
+	var spans [][2]time.Time
+	for _, gap := range gaps {
+		if n := len(spans); n > 0 && gap[1].Sub(spans[n-1][0]) <= limit {
+			spans[n-1][1] = gap[1]
+			continue
+		}
+		spans = append(spans, gap)
+	}
+	return spans
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"moonshot/types"
+)
+
+func TestCandleGaps(t *testing.T) {
+	base := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+
+	// Candles at hours 0, 2 and 5, plus one after the range
+	candles := []types.Candle{{Start: hour(0)}, {Start: hour(2)}, {Start: hour(5)}, {Start: hour(9)}}
+
+	got := candleGaps(candles, hour(0), hour(7), time.Hour)
+	want := [][2]time.Time{{hour(1), hour(2)}, {hour(3), hour(5)}, {hour(6), hour(7)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candleGaps = %v, want %v", got, want)
+	}
+
+	if got := candleGaps(nil, hour(0), hour(4), time.Hour); !reflect.DeepEqual(got, [][2]time.Time{{hour(0), hour(4)}}) {
+		t.Errorf("candleGaps without candles = %v, want the whole range", got)
+	}
+	if got := candleGaps(candles[:2], hour(2), hour(3), time.Hour); got != nil {
+		t.Errorf("candleGaps of a covered range = %v, want none", got)
+	}
+	if got := candleGaps(nil, hour(3), hour(3), time.Hour); got != nil {
+		t.Errorf("candleGaps of an empty range = %v, want none", got)
+	}
+}
+
+func TestFetchSpans(t *testing.T) {
+	base := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+
+	if got := fetchSpans(nil, time.Hour); got != nil {
+		t.Errorf("fetchSpans without gaps = %v, want none", got)
+	}
+
+	// Gaps within one request's worth of candles of the first merge into a single span
+	gaps := [][2]time.Time{{hour(1), hour(2)}, {hour(10), hour(11)}, {hour(200), hour(210)}}
+	if got, want := fetchSpans(gaps, time.Hour), [][2]time.Time{{hour(1), hour(210)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchSpans = %v, want %v", got, want)
+	}
+
+	// Merging stops once a span would need more than one request
+	gaps = [][2]time.Time{{hour(0), hour(1)}, {hour(300), hour(301)}, {hour(310), hour(311)}}
+	if got, want := fetchSpans(gaps, time.Hour), [][2]time.Time{{hour(0), hour(1)}, {hour(300), hour(311)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchSpans = %v, want %v", got, want)
+	}
+
+	// A gap longer than one request is kept whole and not merged with the next
+	gaps = [][2]time.Time{{hour(0), hour(500)}, {hour(501), hour(502)}}
+	if got := fetchSpans(gaps, time.Hour); !reflect.DeepEqual(got, gaps) {
+		t.Errorf("fetchSpans = %v, want %v", got, gaps)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,16 +16,83 @@ import (
 	"github.com/coinbase-samples/advanced-trade-sdk-go/accounts"
 	"github.com/coinbase-samples/advanced-trade-sdk-go/client"
 	"github.com/coinbase-samples/advanced-trade-sdk-go/credentials"
+	"github.com/coinbase-samples/advanced-trade-sdk-go/fees"
 	"github.com/coinbase-samples/advanced-trade-sdk-go/model"
 	"github.com/coinbase-samples/advanced-trade-sdk-go/orders"
 	"github.com/coinbase-samples/advanced-trade-sdk-go/products"
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+// defaultRequestTimeout bounds a single Coinbase API call when no timeout is configured
+const defaultRequestTimeout = 15 * time.Second
+
+// Valuation statuses reported for each portfolio asset
+const (
+	ValuationOK    = "ok"
+	ValuationError = "error"
+)
+
+// Price sources used to value portfolio assets
+const (
+	PriceSourceBid = "bid"
+	PriceSourceAsk = "ask"
+	PriceSourceMid = "mid"
+)
+
+// Candle granularities supported by GetCandles
+const (
+	GranularityOneMinute     = "ONE_MINUTE"
+	GranularityFiveMinute    = "FIVE_MINUTE"
+	GranularityFifteenMinute = "FIFTEEN_MINUTE"
+	GranularityThirtyMinute  = "THIRTY_MINUTE"
+	GranularityOneHour       = "ONE_HOUR"
+	GranularityTwoHour       = "TWO_HOUR"
+	GranularitySixHour       = "SIX_HOUR"
+	GranularityOneDay        = "ONE_DAY"
+)
+
+// granularityDurations is the length of a candle at each granularity
+var granularityDurations = map[string]time.Duration{
+	GranularityOneMinute:     time.Minute,
+	GranularityFiveMinute:    5 * time.Minute,
+	GranularityFifteenMinute: 15 * time.Minute,
+	GranularityThirtyMinute:  30 * time.Minute,
+	GranularityOneHour:       time.Hour,
+	GranularityTwoHour:       2 * time.Hour,
+	GranularitySixHour:       6 * time.Hour,
+	GranularityOneDay:        24 * time.Hour,
+}
+
+// GranularityDuration returns the length of a candle at the given granularity
+func GranularityDuration(granularity string) (time.Duration, error) {
+	step, ok := granularityDurations[granularity]
+	if !ok {
+		return 0, fmt.Errorf("unsupported candle granularity: %s", granularity)
+	}
+	return step, nil
+}
+
+// OrderStatusOpen is the status of an order still working on the book
+const OrderStatusOpen = "OPEN"
+
+// Order statuses reported by Coinbase that mean the order is no longer working
+const (
+	OrderStatusFilled    = "FILLED"
+	OrderStatusCancelled = "CANCELLED"
+	OrderStatusExpired   = "EXPIRED"
+	OrderStatusFailed    = "FAILED"
+)
+
+// ClientOrderIDPrefix marks orders placed by this bot so they can be told apart from manual trades
+const ClientOrderIDPrefix = "moonshot-"
+
 // CoinbaseService handles Coinbase Advanced API operations using the official SDK
 type CoinbaseService struct {
-	config     *types.CoinbaseConfig
-	restClient client.RestClient
+	config      *types.CoinbaseConfig
+	restClient  client.RestClient
+	transport   *instrumentedTransport
+	retryPolicy RetryPolicy
 }
 
 // NewCoinbaseService creates a new Coinbase service instance using the official SDK
@@ -39,20 +109,55 @@ func NewCoinbaseService(config *types.CoinbaseConfig) *CoinbaseService {
 		panic(fmt.Sprintf("unable to load default http client: %v", err))
 	}
 
+	// Time every API request so latency can be exported as metrics, and capture Retry-After for the retry loop
+	transport := newInstrumentedTransport(&retryAfterTransport{base: httpClient.Transport}, coinbaseEndpoint)
+	httpClient.Transport = transport
+
 	// Create REST client
 	restClient := client.NewRestClient(creds, httpClient)
 
 	return &CoinbaseService{
-		config:     config,
-		restClient: restClient,
+		config:      config,
+		restClient:  restClient,
+		transport:   transport,
+		retryPolicy: DefaultRetryPolicy(),
 	}
 }
 
+// SetRetryPolicy sets how failed Coinbase API calls are retried
+func (c *CoinbaseService) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// SetLatencyObserver reports the latency of every Coinbase API request to observer
+func (c *CoinbaseService) SetLatencyObserver(observer LatencyObserver) {
+	c.transport.observer = observer
+}
+
+// call runs an API request bounded by the per-call request timeout, retrying transient failures
+func (c *CoinbaseService) call(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	timeout := c.config.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	return withRetry(ctx, c.retryPolicy, operation, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
 // GetAccounts fetches all accounts using the official SDK
-func (c *CoinbaseService) GetAccounts() ([]*model.Account, error) {
+func (c *CoinbaseService) GetAccounts(ctx context.Context) ([]*model.Account, error) {
 	accountsService := accounts.NewAccountsService(c.restClient)
 
-	resp, err := accountsService.ListAccounts(context.Background(), &accounts.ListAccountsRequest{})
+	var resp *accounts.ListAccountsResponse
+	err := c.call(ctx, "get_accounts", func(ctx context.Context) error {
+		var err error
+		resp, err = accountsService.ListAccounts(ctx, &accounts.ListAccountsRequest{})
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accounts: %w", err)
 	}
@@ -61,11 +166,16 @@ func (c *CoinbaseService) GetAccounts() ([]*model.Account, error) {
 }
 
 // GetProduct fetches product information using the official SDK
-func (c *CoinbaseService) GetProduct(productID string) (*products.GetProductResponse, error) {
+func (c *CoinbaseService) GetProduct(ctx context.Context, productID string) (*products.GetProductResponse, error) {
 	productsService := products.NewProductsService(c.restClient)
 
-	resp, err := productsService.GetProduct(context.Background(), &products.GetProductRequest{
-		ProductId: productID,
+	var resp *products.GetProductResponse
+	err := c.call(ctx, "get_product", func(ctx context.Context) error {
+		var err error
+		resp, err = productsService.GetProduct(ctx, &products.GetProductRequest{
+			ProductId: productID,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product: %w", err)
@@ -75,11 +185,16 @@ func (c *CoinbaseService) GetProduct(productID string) (*products.GetProductResp
 }
 
 // GetProductBook fetches product book information for pricing
-func (c *CoinbaseService) GetProductBook(productID string) (*products.GetProductBookResponse, error) {
+func (c *CoinbaseService) GetProductBook(ctx context.Context, productID string) (*products.GetProductBookResponse, error) {
 	productsService := products.NewProductsService(c.restClient)
 
-	resp, err := productsService.GetProductBook(context.Background(), &products.GetProductBookRequest{
-		ProductId: productID,
+	var resp *products.GetProductBookResponse
+	err := c.call(ctx, "get_product_book", func(ctx context.Context) error {
+		var err error
+		resp, err = productsService.GetProductBook(ctx, &products.GetProductBookRequest{
+			ProductId: productID,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product book: %w", err)
@@ -88,100 +203,519 @@ func (c *CoinbaseService) GetProductBook(productID string) (*products.GetProduct
 	return resp, nil
 }
 
-// PlaceOrder places a new order using the official SDK
-func (c *CoinbaseService) PlaceOrder(productID, side, orderType, size, price string) (*orders.CreateOrderResponse, error) {
+// GetCandles fetches the price bars of a product between start and end, oldest first.
+// Coinbase returns at most 350 candles per request.
+func (c *CoinbaseService) GetCandles(ctx context.Context, productID string, start, end time.Time, granularity string) ([]types.Candle, error) {
+	productsService := products.NewProductsService(c.restClient)
+
+	var resp *products.GetProductCandlesResponse
+	err := c.call(ctx, "get_candles", func(ctx context.Context) error {
+		var err error
+		resp, err = productsService.GetProductCandles(ctx, &products.GetProductCandlesRequest{
+			ProductId:   productID,
+			Start:       strconv.FormatInt(start.Unix(), 10),
+			End:         strconv.FormatInt(end.Unix(), 10),
+			Granularity: granularity,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candles: %w", err)
+	}
+	if resp.Candles == nil {
+		return nil, nil
+	}
+
+	candles := make([]types.Candle, 0, len(*resp.Candles))
+	for _, raw := range *resp.Candles {
+		candle, err := parseCandle(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid candle for %s: %w", productID, err)
+		}
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Start.Before(candles[j].Start)
+	})
+
+	return candles, nil
+}
+
+// maxCandlesPerRequest keeps each page of GetCandleHistory within the Coinbase limit
+const maxCandlesPerRequest = 300
+
+// GetCandleHistory fetches the price bars of a product between start and end, oldest first,
+// paging through as many requests as the range needs
+func (c *CoinbaseService) GetCandleHistory(ctx context.Context, productID string, start, end time.Time, granularity string) ([]types.Candle, error) {
+	step, err := GranularityDuration(granularity)
+	if err != nil {
+		return nil, err
+	}
+	page := step * maxCandlesPerRequest
+
+	var history []types.Candle
+	for pageStart := start; pageStart.Before(end); pageStart = pageStart.Add(page) {
+		pageEnd := pageStart.Add(page)
+		if pageEnd.After(end) {
+			pageEnd = end
+		}
+
+		candles, err := c.GetCandles(ctx, productID, pageStart, pageEnd, granularity)
+		if err != nil {
+			return nil, err
+		}
+		// Pages share their boundary candle
+		for _, candle := range candles {
+			if len(history) == 0 || candle.Start.After(history[len(history)-1].Start) {
+				history = append(history, candle)
+			}
+		}
+	}
+
+	return history, nil
+}
+
+// parseCandle converts an SDK candle with a Unix start time and string prices
+func parseCandle(raw model.Candle) (types.Candle, error) {
+	start, err := strconv.ParseInt(raw.Start, 10, 64)
+	if err != nil {
+		return types.Candle{}, fmt.Errorf("invalid start %q", raw.Start)
+	}
+
+	candle := types.Candle{Start: time.Unix(start, 0).UTC()}
+	fields := []struct {
+		value  string
+		target *decimal.Decimal
+	}{
+		{raw.Open, &candle.Open},
+		{raw.High, &candle.High},
+		{raw.Low, &candle.Low},
+		{raw.Close, &candle.Close},
+		{raw.Volume, &candle.Volume},
+	}
+	for _, field := range fields {
+		if *field.target, err = decimal.NewFromString(field.value); err != nil {
+			return types.Candle{}, fmt.Errorf("invalid price %q", field.value)
+		}
+	}
+
+	return candle, nil
+}
+
+// PlaceOrder places a new order using the official SDK. Every attempt reuses the same client order ID,
+// which Coinbase deduplicates, so retrying after a lost response cannot place the order twice.
+// The post_only order type is a GTC limit order that is rejected instead of taking liquidity.
+// Size is in USDC for market buys and in base units otherwise.
+func (c *CoinbaseService) PlaceOrder(ctx context.Context, productID, side, orderType, size, price string) (*orders.CreateOrderResponse, error) {
 	ordersService := orders.NewOrdersService(c.restClient)
 
 	// Create order configuration based on order type
 	var orderConfig model.OrderConfiguration
 
-	if orderType == "market" {
+	if orderType == "market" && side == "SELL" {
+		// Market sells are sized in base units
+		orderConfig = model.OrderConfiguration{
+			MarketMarketIoc: &model.MarketIoc{
+				BaseSize: size,
+			},
+		}
+	} else if orderType == "market" {
+		// Market buys are sized in quote units
 		orderConfig = model.OrderConfiguration{
 			MarketMarketIoc: &model.MarketIoc{
 				QuoteSize: size,
 			},
 		}
-	} else if orderType == "limit" && price != "" {
+	} else if (orderType == "limit" || orderType == "post_only") && price != "" {
 		orderConfig = model.OrderConfiguration{
 			LimitLimitGtc: &model.LimitGtc{
 				BaseSize:   size,
 				LimitPrice: price,
+				PostOnly:   orderType == "post_only",
 			},
 		}
 	} else {
 		return nil, fmt.Errorf("unsupported order type: %s", orderType)
 	}
 
-	resp, err := ordersService.CreateOrder(context.Background(), &orders.CreateOrderRequest{
+	clientOrderID := NewClientOrderID()
+	slog.Debug("Submitting order",
+		"product_id", productID, "side", side, "order_type", orderType, "size", size, "client_order_id", clientOrderID)
+
+	request := &orders.CreateOrderRequest{
 		ProductId:          productID,
 		Side:               side,
+		ClientOrderId:      clientOrderID,
 		OrderConfiguration: orderConfig,
+	}
+
+	var resp *orders.CreateOrderResponse
+	err := c.call(ctx, "place_order", func(ctx context.Context) error {
+		var err error
+		resp, err = ordersService.CreateOrder(ctx, request)
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to place order: %w", err)
 	}
 
+	slog.Debug("Order submitted", "product_id", productID, "order_id", resp.OrderId, "success", resp.Success)
+
 	return resp, nil
 }
 
-// GetPortfolio fetches current portfolio information using the official SDK
-func (c *CoinbaseService) GetPortfolio() (*types.Portfolio, error) {
-	accounts, err := c.GetAccounts()
+// CancelOrder cancels an open order using the official SDK
+func (c *CoinbaseService) CancelOrder(ctx context.Context, orderID string) error {
+	ordersService := orders.NewOrdersService(c.restClient)
+
+	var resp *orders.CancelOrdersResponse
+	err := c.call(ctx, "cancel_order", func(ctx context.Context) error {
+		var err error
+		resp, err = ordersService.CancelOrders(ctx, &orders.CancelOrdersRequest{
+			OrderIds: []string{orderID},
+		})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to cancel order: %w", err)
 	}
 
-	portfolio := &types.Portfolio{
-		Assets:      make(map[string]*types.Asset),
-		LastUpdated: time.Now(),
+	for _, result := range resp.Results {
+		if result.OrderId == orderID && !result.Success {
+			return fmt.Errorf("failed to cancel order %s: %s", orderID, result.FailureReason)
+		}
 	}
 
-	totalValue := decimal.Zero
+	return nil
+}
+
+// GetFeeRates fetches the account's current maker and taker fee rates
+func (c *CoinbaseService) GetFeeRates(ctx context.Context) (decimal.Decimal, decimal.Decimal, error) {
+	feesService := fees.NewFeesService(c.restClient)
 
-	for _, account := range accounts {
-		balance, _ := decimal.NewFromString(account.AvailableBalance.Value)
-		if balance.IsZero() {
-			continue
+	var resp *fees.GetTransactionsSummaryResponse
+	err := c.call(ctx, "get_fee_rates", func(ctx context.Context) error {
+		var err error
+		resp, err = feesService.GetTransactionsSummary(ctx, &fees.GetTransactionsSummaryRequest{})
+		return err
+	})
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("failed to get fee rates: %w", err)
+	}
+
+	maker, err := decimal.NewFromString(resp.FeeTier.MakerFeeRate)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("failed to parse maker fee rate: %w", err)
+	}
+	taker, err := decimal.NewFromString(resp.FeeTier.TakerFeeRate)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("failed to parse taker fee rate: %w", err)
+	}
+
+	return maker, taker, nil
+}
+
+// NewClientOrderID generates a unique client order ID carrying the bot prefix
+func NewClientOrderID() string {
+	return ClientOrderIDPrefix + uuid.NewString()
+}
+
+// IsBotClientOrderID reports whether a client order ID was generated by this bot
+func IsBotClientOrderID(clientOrderID string) bool {
+	return strings.HasPrefix(clientOrderID, ClientOrderIDPrefix)
+}
+
+// GetOrder fetches an order, including its fill details, using the official SDK
+func (c *CoinbaseService) GetOrder(ctx context.Context, orderID string) (*model.Order, error) {
+	ordersService := orders.NewOrdersService(c.restClient)
+
+	var resp *orders.GetOrderResponse
+	err := c.call(ctx, "get_order", func(ctx context.Context) error {
+		var err error
+		resp, err = ordersService.GetOrder(ctx, &orders.GetOrderRequest{
+			OrderId: orderID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	if resp.Order == nil {
+		return nil, fmt.Errorf("order %s not found", orderID)
+	}
+
+	return resp.Order, nil
+}
+
+// ListFills fetches all fills for a product since the given time.
+// The SDK does not forward the pagination cursor for fills, so pages are walked backwards
+// in time using the sequence timestamp of the oldest fill seen so far.
+func (c *CoinbaseService) ListFills(ctx context.Context, productID string, since time.Time) ([]*model.Fill, error) {
+	ordersService := orders.NewOrdersService(c.restClient)
+
+	const pageSize = 250
+	request := &orders.ListFillsRequest{
+		ProductId: productID,
+		Limit:     fmt.Sprintf("%d", pageSize),
+	}
+	if !since.IsZero() {
+		request.StartSequenceTimestamp = since.UTC().Format(time.RFC3339Nano)
+	}
+
+	var fills []*model.Fill
+	seen := make(map[string]bool)
+	for {
+		var resp *orders.ListFillsResponse
+		err := c.call(ctx, "list_fills", func(ctx context.Context) error {
+			var err error
+			resp, err = ordersService.ListFills(ctx, request)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fills: %w", err)
 		}
 
-		if account.Currency == "USDC" {
-			portfolio.USDCBalance = balance
-			totalValue = totalValue.Add(balance)
-		} else if account.Currency == "BTC" || account.Currency == "ETH" {
-			// Get current price from product book
-			productID := account.Currency + "-USDC"
-			productBookResp, err := c.GetProductBook(productID)
-			if err != nil {
+		added := 0
+		var oldest time.Time
+		for _, fill := range resp.Fills {
+			if oldest.IsZero() || fill.SequenceTimestamp.Before(oldest) {
+				oldest = fill.SequenceTimestamp
+			}
+			if seen[fill.EntryId] {
 				continue
 			}
+			seen[fill.EntryId] = true
+			fills = append(fills, fill)
+			added++
+		}
+
+		if len(resp.Fills) < pageSize || added == 0 || oldest.IsZero() {
+			break
+		}
+		request.EndSequenceTimestamp = oldest.UTC().Format(time.RFC3339Nano)
+	}
+
+	return fills, nil
+}
+
+// ListOrders fetches all orders for a product created since the given time.
+// Like fills, pages are walked backwards in time since the SDK does not forward the cursor.
+func (c *CoinbaseService) ListOrders(ctx context.Context, productID string, since time.Time) ([]*model.Order, error) {
+	request := &orders.ListOrdersRequest{
+		ProductIds: []string{productID},
+	}
+	if !since.IsZero() {
+		request.StartDate = since.UTC().Format(time.RFC3339)
+	}
+	return c.listOrders(ctx, request)
+}
+
+// ListOpenOrders fetches the orders for a product that are still working on the book
+func (c *CoinbaseService) ListOpenOrders(ctx context.Context, productID string) ([]*model.Order, error) {
+	return c.listOrders(ctx, &orders.ListOrdersRequest{
+		ProductIds:  []string{productID},
+		OrderStatus: []string{OrderStatusOpen},
+	})
+}
+
+// listOrders fetches every page of orders matching the request
+func (c *CoinbaseService) listOrders(ctx context.Context, request *orders.ListOrdersRequest) ([]*model.Order, error) {
+	ordersService := orders.NewOrdersService(c.restClient)
 
-			// Use the best bid price as current price
-			var price decimal.Decimal
-			if productBookResp.PriceBook != nil && len(productBookResp.PriceBook.Bids) > 0 {
-				price, _ = decimal.NewFromString(productBookResp.PriceBook.Bids[0].Price)
-			} else if productBookResp.PriceBook != nil && len(productBookResp.PriceBook.Asks) > 0 {
-				price, _ = decimal.NewFromString(productBookResp.PriceBook.Asks[0].Price)
-			} else {
+	var result []*model.Order
+	seen := make(map[string]bool)
+	for {
+		var resp *orders.ListOrdersResponse
+		err := c.call(ctx, "list_orders", func(ctx context.Context) error {
+			var err error
+			resp, err = ordersService.ListOrders(ctx, request)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list orders: %w", err)
+		}
+
+		added := 0
+		oldest := ""
+		for _, order := range resp.Orders {
+			// RFC 3339 timestamps in UTC sort lexically
+			if oldest == "" || order.CreatedTime < oldest {
+				oldest = order.CreatedTime
+			}
+			if seen[order.OrderId] {
 				continue
 			}
+			seen[order.OrderId] = true
+			result = append(result, order)
+			added++
+		}
+
+		if added == 0 || oldest == "" {
+			break
+		}
+		request.EndDate = oldest
+	}
+
+	return result, nil
+}
+
+// GetPortfolio fetches the current portfolio and values the given assets in USDC.
+// Balances include funds on hold. An asset that cannot be valued is reported with an error
+// status instead of being left out, so callers can tell a failure from a smaller portfolio.
+func (c *CoinbaseService) GetPortfolio(ctx context.Context, symbols []string) (*types.Portfolio, error) {
+	// Fetch accounts and every order book concurrently
+	type bookPrice struct {
+		price  decimal.Decimal
+		source string
+		err    error
+	}
+	var (
+		accounts    []*model.Account
+		accountsErr error
+		prices      = make([]bookPrice, len(symbols))
+	)
+
+	group := newLimitGroup(c.config.MaxConcurrency)
+	group.Go(func() {
+		accounts, accountsErr = c.GetAccounts(ctx)
+	})
+	for i, symbol := range symbols {
+		i, symbol := i, symbol
+		group.Go(func() {
+			prices[i].price, prices[i].source, prices[i].err = c.getBookPrice(ctx, symbol+"-USDC")
+		})
+	}
+	group.Wait()
+
+	if accountsErr != nil {
+		return nil, accountsErr
+	}
+
+	portfolio := &types.Portfolio{
+		Assets:      make(map[string]*types.Asset),
+		LastUpdated: time.Now(),
+	}
+
+	// Every requested asset is reported, even without an account or balance
+	for _, symbol := range symbols {
+		portfolio.Assets[symbol] = &types.Asset{
+			Symbol: symbol,
+			Name:   symbol,
+			Status: ValuationOK,
+		}
+	}
 
-			assetValue := balance.Mul(price)
-			totalValue = totalValue.Add(assetValue)
+	for _, account := range accounts {
+		available, availableErr := parseAmount(account.AvailableBalance)
+		hold, holdErr := parseAmount(account.Hold)
 
-			portfolio.Assets[account.Currency] = &types.Asset{
-				Symbol:  account.Currency,
-				Name:    account.Currency,
-				Balance: balance,
-				Price:   price,
-				Value:   assetValue,
+		if account.Currency == "USDC" {
+			if availableErr != nil {
+				return nil, fmt.Errorf("failed to parse USDC balance: %w", availableErr)
+			}
+			if holdErr != nil {
+				return nil, fmt.Errorf("failed to parse USDC hold: %w", holdErr)
 			}
+			portfolio.USDCBalance = portfolio.USDCBalance.Add(available)
+			portfolio.USDCHold = portfolio.USDCHold.Add(hold)
+			continue
+		}
+
+		asset, ok := portfolio.Assets[account.Currency]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case availableErr != nil:
+			asset.Status, asset.Error = ValuationError, fmt.Sprintf("failed to parse available balance: %v", availableErr)
+		case holdErr != nil:
+			asset.Status, asset.Error = ValuationError, fmt.Sprintf("failed to parse hold: %v", holdErr)
+		default:
+			asset.Available = asset.Available.Add(available)
+			asset.Hold = asset.Hold.Add(hold)
+			asset.Balance = asset.Available.Add(asset.Hold)
+		}
+	}
+
+	totalValue := portfolio.USDCBalance.Add(portfolio.USDCHold)
+	for i, symbol := range symbols {
+		asset := portfolio.Assets[symbol]
+		if asset.Status != ValuationOK {
+			continue
 		}
+
+		if prices[i].err != nil {
+			asset.Status, asset.Error = ValuationError, prices[i].err.Error()
+			continue
+		}
+
+		asset.Price = prices[i].price
+		asset.PriceSource = prices[i].source
+		asset.Value = asset.Balance.Mul(asset.Price)
+		totalValue = totalValue.Add(asset.Value)
 	}
 
 	portfolio.TotalValue = totalValue
 	return portfolio, nil
 }
 
+// getBookPrice prices a product from its order book using the configured price source (bid by default).
+// If that side of the book is empty the other side is used, and the source actually used is returned.
+func (c *CoinbaseService) getBookPrice(ctx context.Context, productID string) (decimal.Decimal, string, error) {
+	bid, ask, err := c.GetBestBidAsk(ctx, productID)
+	if err != nil {
+		return decimal.Zero, "", err
+	}
+
+	switch {
+	case c.config.PriceSource == PriceSourceMid && bid.IsPositive() && ask.IsPositive():
+		return bid.Add(ask).Div(decimal.NewFromInt(2)), PriceSourceMid, nil
+	case c.config.PriceSource == PriceSourceAsk && ask.IsPositive():
+		return ask, PriceSourceAsk, nil
+	case bid.IsPositive():
+		return bid, PriceSourceBid, nil
+	case ask.IsPositive():
+		return ask, PriceSourceAsk, nil
+	default:
+		return decimal.Zero, "", fmt.Errorf("no quotes in product book for %s", productID)
+	}
+}
+
+// GetBestBidAsk returns the top of the order book; a side without quotes is returned as zero
+func (c *CoinbaseService) GetBestBidAsk(ctx context.Context, productID string) (decimal.Decimal, decimal.Decimal, error) {
+	book, err := c.GetProductBook(ctx, productID)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	if book.PriceBook == nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("empty product book for %s", productID)
+	}
+
+	var bid, ask decimal.Decimal
+	if len(book.PriceBook.Bids) > 0 {
+		if bid, err = decimal.NewFromString(book.PriceBook.Bids[0].Price); err != nil {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("invalid bid price %q for %s", book.PriceBook.Bids[0].Price, productID)
+		}
+	}
+	if len(book.PriceBook.Asks) > 0 {
+		if ask, err = decimal.NewFromString(book.PriceBook.Asks[0].Price); err != nil {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("invalid ask price %q for %s", book.PriceBook.Asks[0].Price, productID)
+		}
+	}
+
+	return bid, ask, nil
+}
+
+// parseAmount parses an account amount, treating an empty value as zero
+func parseAmount(amount model.Amount) (decimal.Decimal, error) {
+	if amount.Value == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(amount.Value)
+}
+
 // LoadCredentialsFromEnv loads credentials from environment variables in the format expected by the official SDK
 func LoadCredentialsFromEnv() (*credentials.Credentials, error) {
 	// Check if credentials are provided as a JSON string
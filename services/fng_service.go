@@ -1,10 +1,15 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"time"
 
 	"moonshot/types"
@@ -14,8 +19,10 @@ import (
 
 // FNGService handles Fear & Greed Index operations
 type FNGService struct {
-	apiURL string
-	client *http.Client
+	apiURL      string
+	client      *http.Client
+	transport   *instrumentedTransport
+	retryPolicy RetryPolicy
 }
 
 // FNGResponse represents the API response from alternative.me
@@ -30,30 +37,40 @@ type FNGResponse struct {
 
 // NewFNGService creates a new FNG service instance
 func NewFNGService(apiURL string) *FNGService {
+	transport := newInstrumentedTransport(nil, func(req *http.Request) string {
+		return req.Method + " fng"
+	})
 	return &FNGService{
 		apiURL: apiURL,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
+		transport:   transport,
+		retryPolicy: DefaultRetryPolicy(),
 	}
 }
 
-// GetFearGreedIndex fetches the current Fear & Greed Index
-func (f *FNGService) GetFearGreedIndex() (*types.FearGreedIndex, error) {
-	resp, err := f.client.Get(f.apiURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch FNG index: %w", err)
-	}
-	defer resp.Body.Close()
+// SetRetryPolicy sets how failed FNG API calls are retried
+func (f *FNGService) SetRetryPolicy(policy RetryPolicy) {
+	f.retryPolicy = policy
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+// SetLatencyObserver reports the latency of every FNG API request to observer
+func (f *FNGService) SetLatencyObserver(observer LatencyObserver) {
+	f.transport.observer = observer
+}
 
-	var fngResp FNGResponse
-	if err := json.Unmarshal(body, &fngResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal FNG response: %w", err)
+// GetFearGreedIndex fetches the current Fear & Greed Index
+func (f *FNGService) GetFearGreedIndex(ctx context.Context) (*types.FearGreedIndex, error) {
+	var fngResp *FNGResponse
+	err := withRetry(ctx, f.retryPolicy, "get_fng", func(ctx context.Context) error {
+		var err error
+		fngResp, err = f.fetch(ctx, f.apiURL)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	if len(fngResp.Data) == 0 {
@@ -67,6 +84,7 @@ func (f *FNGService) GetFearGreedIndex() (*types.FearGreedIndex, error) {
 	}
 
 	multiplier := f.calculateMultiplier(value)
+	slog.Debug("Fetched FNG index", "fng", value, "classification", data.Classification)
 
 	return &types.FearGreedIndex{
 		Value:          value,
@@ -76,10 +94,94 @@ func (f *FNGService) GetFearGreedIndex() (*types.FearGreedIndex, error) {
 	}, nil
 }
 
+// GetFearGreedHistory fetches the daily Fear & Greed Index values of the last days, oldest first.
+// Zero days fetches the whole history.
+func (f *FNGService) GetFearGreedHistory(ctx context.Context, days int) ([]types.FearGreedIndex, error) {
+	historyURL, err := url.Parse(f.apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FNG API URL: %w", err)
+	}
+	query := historyURL.Query()
+	query.Set("limit", strconv.Itoa(days))
+	historyURL.RawQuery = query.Encode()
+
+	var fngResp *FNGResponse
+	err = withRetry(ctx, f.retryPolicy, "get_fng_history", func(ctx context.Context) error {
+		var err error
+		fngResp, err = f.fetch(ctx, historyURL.String())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]types.FearGreedIndex, 0, len(fngResp.Data))
+	for _, data := range fngResp.Data {
+		value, err := strconv.Atoi(data.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse FNG value %q: %w", data.Value, err)
+		}
+		seconds, err := strconv.ParseInt(data.Timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse FNG timestamp %q: %w", data.Timestamp, err)
+		}
+		history = append(history, types.FearGreedIndex{
+			Value:          value,
+			Classification: data.Classification,
+			Timestamp:      time.Unix(seconds, 0).UTC(),
+			Multiplier:     FNGMultiplier(value),
+		})
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	return history, nil
+}
+
+// fetch performs a single request to the FNG API
+func (f *FNGService) fetch(ctx context.Context, apiURL string) (*FNGResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FNG request: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch FNG index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch FNG index: %w", &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Message:    string(body),
+		})
+	}
+
+	var fngResp FNGResponse
+	if err := json.Unmarshal(body, &fngResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal FNG response: %w", err)
+	}
+
+	return &fngResp, nil
+}
+
 // calculateMultiplier calculates the investment multiplier based on F&G value
+func (f *FNGService) calculateMultiplier(value int) decimal.Decimal {
+	return FNGMultiplier(value)
+}
+
+// FNGMultiplier returns the investment multiplier for an F&G value
 // Lower F&G values (fear) = higher multiplier (buy more)
 // Higher F&G values (greed) = lower multiplier (buy less)
-func (f *FNGService) calculateMultiplier(value int) decimal.Decimal {
+func FNGMultiplier(value int) decimal.Decimal {
 	// F&G ranges from 0-100
 	// 0-25: Extreme Fear (multiplier: 1.5-2.0)
 	// 26-45: Fear (multiplier: 1.2-1.5)
@@ -0,0 +1,38 @@
+package services
+
+import "sync"
+
+// defaultMaxConcurrency bounds concurrent API requests when no limit is configured
+const defaultMaxConcurrency = 4
+
+// limitGroup runs functions concurrently with at most a fixed number running at once
+type limitGroup struct {
+	wg  sync.WaitGroup
+	sem chan struct{}
+}
+
+// newLimitGroup creates a group running at most limit functions at once
+func newLimitGroup(limit int) *limitGroup {
+	if limit <= 0 {
+		limit = defaultMaxConcurrency
+	}
+	return &limitGroup{
+		sem: make(chan struct{}, limit),
+	}
+}
+
+// Go runs fn in a new goroutine once a slot is free
+func (g *limitGroup) Go(fn func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.sem <- struct{}{}
+		defer func() { <-g.sem }()
+		fn()
+	}()
+}
+
+// Wait blocks until every function has returned
+func (g *limitGroup) Wait() {
+	g.wg.Wait()
+}
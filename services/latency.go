@@ -0,0 +1,58 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LatencyObserver receives the duration of every API request, labeled by endpoint
+type LatencyObserver func(endpoint string, duration time.Duration)
+
+// instrumentedTransport times requests and reports them to an optional latency observer
+type instrumentedTransport struct {
+	base     http.RoundTripper
+	endpoint func(req *http.Request) string
+	observer LatencyObserver
+}
+
+// newInstrumentedTransport wraps base, labeling requests with the given endpoint function
+func newInstrumentedTransport(base http.RoundTripper, endpoint func(req *http.Request) string) *instrumentedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &instrumentedTransport{
+		base:     base,
+		endpoint: endpoint,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if t.observer != nil {
+		t.observer(t.endpoint(req), time.Since(start))
+	}
+
+	return resp, err
+}
+
+// coinbaseEndpoint normalizes an Advanced Trade API path into a low-cardinality endpoint label,
+// replacing product and order IDs with placeholders
+func coinbaseEndpoint(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/api/v3/brokerage")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case (len(segments) == 2 || len(segments) == 3) && segments[0] == "products":
+		segments[1] = "{product_id}"
+	case len(segments) == 2 && segments[0] == "accounts":
+		segments[1] = "{account_id}"
+	case len(segments) == 3 && segments[0] == "orders" && segments[1] == "historical" &&
+		segments[2] != "fills" && segments[2] != "batch":
+		segments[2] = "{order_id}"
+	}
+
+	return req.Method + " /" + strings.Join(segments, "/")
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultMarketDataURL is the public Coinbase Advanced Trade market data WebSocket endpoint
+const DefaultMarketDataURL = "wss://advanced-trade-ws.coinbase.com"
+
+const (
+	// heartbeatTimeout is how long the connection may stay silent before it is considered dead.
+	// The heartbeats channel sends a message every second.
+	heartbeatTimeout = 30 * time.Second
+
+	// quoteMaxAge is how old a quote may be before it is no longer served
+	quoteMaxAge = time.Minute
+
+	// reconnect backoff bounds
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// wsSubscription is a channel subscription request
+type wsSubscription struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids,omitempty"`
+	Channel    string   `json:"channel"`
+}
+
+// wsMessage is the envelope of every market data message
+type wsMessage struct {
+	Channel     string            `json:"channel"`
+	Timestamp   time.Time         `json:"timestamp"`
+	SequenceNum int64             `json:"sequence_num"`
+	Events      []json.RawMessage `json:"events"`
+}
+
+// wsTickerEvent is an event of the ticker channel
+type wsTickerEvent struct {
+	Type    string `json:"type"`
+	Tickers []struct {
+		ProductID string `json:"product_id"`
+		Price     string `json:"price"`
+		BestBid   string `json:"best_bid"`
+		BestAsk   string `json:"best_ask"`
+	} `json:"tickers"`
+}
+
+// wsLevel2Event is an event of the level2 channel
+type wsLevel2Event struct {
+	Type      string `json:"type"` // snapshot or update
+	ProductID string `json:"product_id"`
+	Updates   []struct {
+		Side        string `json:"side"` // bid or offer
+		PriceLevel  string `json:"price_level"`
+		NewQuantity string `json:"new_quantity"`
+	} `json:"updates"`
+}
+
+// orderBook is the level2 book of a product, keyed by price level. The top of book is cached
+// and only recomputed when the best level is removed.
+type orderBook struct {
+	bids    map[string]decimal.Decimal
+	asks    map[string]decimal.Decimal
+	bestBid decimal.Decimal
+	bestAsk decimal.Decimal
+}
+
+// newOrderBook creates an empty order book
+func newOrderBook() *orderBook {
+	return &orderBook{
+		bids: make(map[string]decimal.Decimal),
+		asks: make(map[string]decimal.Decimal),
+	}
+}
+
+// apply sets the quantity at a price level; a zero quantity removes the level
+func (b *orderBook) apply(side, level string, quantity decimal.Decimal) {
+	price, err := decimal.NewFromString(level)
+	if err != nil {
+		return
+	}
+
+	isBid := side == "bid"
+	levels := b.asks
+	if isBid {
+		levels = b.bids
+	}
+
+	if quantity.IsZero() {
+		delete(levels, level)
+		if isBid && price.Equal(b.bestBid) {
+			b.bestBid = bestLevel(b.bids, decimal.Decimal.GreaterThan)
+		} else if !isBid && price.Equal(b.bestAsk) {
+			b.bestAsk = bestLevel(b.asks, decimal.Decimal.LessThan)
+		}
+		return
+	}
+
+	levels[level] = quantity
+	if isBid && price.GreaterThan(b.bestBid) {
+		b.bestBid = price
+	} else if !isBid && (b.bestAsk.IsZero() || price.LessThan(b.bestAsk)) {
+		b.bestAsk = price
+	}
+}
+
+// bestLevel scans the levels for the best price according to better
+func bestLevel(levels map[string]decimal.Decimal, better func(decimal.Decimal, decimal.Decimal) bool) decimal.Decimal {
+	var best decimal.Decimal
+	for level := range levels {
+		if price, err := decimal.NewFromString(level); err == nil && (best.IsZero() || better(price, best)) {
+			best = price
+		}
+	}
+	return best
+}
+
+// MarketDataService maintains live quotes for a set of products from the Coinbase WebSocket feed.
+// It subscribes to the ticker and level2 channels, plus heartbeats to keep the connection alive,
+// and reconnects with backoff whenever the connection drops or goes silent.
+type MarketDataService struct {
+	url        string
+	productIDs []string
+	dialer     *websocket.Dialer
+	timeout    time.Duration // silence after which the connection is considered dead
+
+	mu     sync.RWMutex
+	quotes map[string]*types.Quote
+	books  map[string]*orderBook
+}
+
+// NewMarketDataService creates a market data service for the given products
+func NewMarketDataService(url string, productIDs []string) *MarketDataService {
+	if url == "" {
+		url = DefaultMarketDataURL
+	}
+	return &MarketDataService{
+		url:        url,
+		productIDs: productIDs,
+		dialer:     websocket.DefaultDialer,
+		timeout:    heartbeatTimeout,
+		quotes:     make(map[string]*types.Quote),
+		books:      make(map[string]*orderBook),
+	}
+}
+
+// Quote returns the latest quote for a product, or false if there is no recent quote
+func (m *MarketDataService) Quote(productID string) (types.Quote, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	quote, ok := m.quotes[productID]
+	if !ok || time.Since(quote.Timestamp) > quoteMaxAge {
+		return types.Quote{}, false
+	}
+	return *quote, true
+}
+
+// Run streams market data until the context is cancelled, reconnecting after failures
+func (m *MarketDataService) Run(ctx context.Context) error {
+	delay := minReconnectDelay
+	for {
+		connected, err := m.stream(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// A connection that received data resets the backoff
+		if connected {
+			delay = minReconnectDelay
+		}
+		slog.Warn("Market data connection lost, reconnecting", "error", err, "delay", delay.String())
+		m.reset()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// stream runs a single connection, returning whether any data was received before it ended
+func (m *MarketDataService) stream(ctx context.Context) (bool, error) {
+	conn, _, err := m.dialer.DialContext(ctx, m.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to market data feed: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for _, channel := range []string{"heartbeats", "ticker", "level2"} {
+		subscription := wsSubscription{Type: "subscribe", Channel: channel}
+		if channel != "heartbeats" {
+			subscription.ProductIDs = m.productIDs
+		}
+		if err := conn.WriteJSON(subscription); err != nil {
+			return false, fmt.Errorf("failed to subscribe to %s: %w", channel, err)
+		}
+	}
+	slog.Info("Market data feed connected", "url", m.url, "products", m.productIDs)
+
+	received := false
+	sequence := int64(-1)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(m.timeout)); err != nil {
+			return received, err
+		}
+
+		var message wsMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return received, fmt.Errorf("failed to read market data: %w", err)
+		}
+		received = true
+
+		// A gap in the sequence means a missed level2 update, so the books can't be trusted
+		if sequence >= 0 && message.SequenceNum != sequence+1 {
+			return received, fmt.Errorf("market data sequence gap: expected %d, got %d", sequence+1, message.SequenceNum)
+		}
+		sequence = message.SequenceNum
+
+		if err := m.handle(message); err != nil {
+			return received, err
+		}
+	}
+}
+
+// handle applies a market data message to the quotes and books
+func (m *MarketDataService) handle(message wsMessage) error {
+	timestamp := message.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	switch message.Channel {
+	case "ticker":
+		for _, raw := range message.Events {
+			var event wsTickerEvent
+			if err := json.Unmarshal(raw, &event); err != nil {
+				return fmt.Errorf("failed to decode ticker event: %w", err)
+			}
+			for _, ticker := range event.Tickers {
+				m.updateTicker(ticker.ProductID, ticker.Price, ticker.BestBid, ticker.BestAsk, timestamp)
+			}
+		}
+	case "l2_data":
+		for _, raw := range message.Events {
+			var event wsLevel2Event
+			if err := json.Unmarshal(raw, &event); err != nil {
+				return fmt.Errorf("failed to decode level2 event: %w", err)
+			}
+			m.updateBook(event, timestamp)
+		}
+	case "heartbeats", "subscriptions":
+		// Only keep the connection alive
+	}
+
+	return nil
+}
+
+// updateTicker records the last trade and top of book reported by the ticker channel
+func (m *MarketDataService) updateTicker(productID, price, bestBid, bestAsk string, timestamp time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quote := m.quote(productID)
+	if value, err := decimal.NewFromString(price); err == nil {
+		quote.LastPrice = value
+	}
+	// The level2 book is more current than the ticker's top of book, when available
+	if _, ok := m.books[productID]; !ok {
+		if value, err := decimal.NewFromString(bestBid); err == nil {
+			quote.BestBid = value
+		}
+		if value, err := decimal.NewFromString(bestAsk); err == nil {
+			quote.BestAsk = value
+		}
+	}
+	quote.Timestamp = timestamp
+}
+
+// updateBook applies a level2 snapshot or update and refreshes the top of book
+func (m *MarketDataService) updateBook(event wsLevel2Event, timestamp time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[event.ProductID]
+	if !ok || event.Type == "snapshot" {
+		book = newOrderBook()
+		m.books[event.ProductID] = book
+	}
+
+	for _, update := range event.Updates {
+		quantity, err := decimal.NewFromString(update.NewQuantity)
+		if err != nil {
+			quantity = decimal.Zero
+		}
+		book.apply(update.Side, update.PriceLevel, quantity)
+	}
+
+	quote := m.quote(event.ProductID)
+	quote.BestBid, quote.BestAsk = book.bestBid, book.bestAsk
+	quote.Timestamp = timestamp
+}
+
+// quote returns the quote for a product, creating it if needed; the caller must hold the lock
+func (m *MarketDataService) quote(productID string) *types.Quote {
+	quote, ok := m.quotes[productID]
+	if !ok {
+		quote = &types.Quote{ProductID: productID}
+		m.quotes[productID] = quote
+	}
+	return quote
+}
+
+// reset drops all quotes and books after a disconnect so stale prices are never served
+func (m *MarketDataService) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotes = make(map[string]*types.Quote)
+	m.books = make(map[string]*orderBook)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// feedServer is a fake market data feed. Each connection reads the subscriptions, then is handed
+// to serve along with its 1-based connection number.
+func feedServer(t *testing.T, serve func(conn *websocket.Conn, n int)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	connections := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for i := 0; i < 3; i++ {
+			var subscription wsSubscription
+			if err := conn.ReadJSON(&subscription); err != nil {
+				return
+			}
+		}
+		serve(conn, int(connections.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+	return server, connections
+}
+
+// newTestMarketData creates a market data service connected to a fake feed
+func newTestMarketData(server *httptest.Server) *MarketDataService {
+	return NewMarketDataService("ws"+strings.TrimPrefix(server.URL, "http"), []string{"BTC-USDC"})
+}
+
+// send writes a market data message with the given channel, sequence number and raw events
+func send(t *testing.T, conn *websocket.Conn, channel string, sequence int64, events ...string) {
+	t.Helper()
+
+	message := `{"channel":"` + channel + `","timestamp":"` + time.Now().UTC().Format(time.RFC3339Nano) +
+		`","sequence_num":` + strconv.FormatInt(sequence, 10) + `,"events":[` + strings.Join(events, ",") + `]}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Errorf("failed to send message: %v", err)
+	}
+}
+
+// eventually polls cond until it holds or the timeout passes
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// runFeed runs the service in the background until the test ends
+func runFeed(t *testing.T, m *MarketDataService) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestMarketDataTicker(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	server, _ := feedServer(t, func(conn *websocket.Conn, n int) {
+		send(t, conn, "ticker", 0,
+			`{"type":"update","tickers":[{"product_id":"BTC-USDC","price":"60000.5","best_bid":"60000","best_ask":"60001"}]}`)
+		<-hold
+	})
+
+	m := newTestMarketData(server)
+	runFeed(t, m)
+
+	if !eventually(t, 2*time.Second, func() bool { _, ok := m.Quote("BTC-USDC"); return ok }) {
+		t.Fatal("no quote received")
+	}
+	quote, _ := m.Quote("BTC-USDC")
+	if !quote.LastPrice.Equal(decimal.RequireFromString("60000.5")) ||
+		!quote.BestBid.Equal(decimal.NewFromInt(60000)) ||
+		!quote.BestAsk.Equal(decimal.NewFromInt(60001)) {
+		t.Errorf("quote = last %s bid %s ask %s, want 60000.5 60000 60001", quote.LastPrice, quote.BestBid, quote.BestAsk)
+	}
+	if _, ok := m.Quote("ETH-USDC"); ok {
+		t.Error("got a quote for a product without data")
+	}
+}
+
+func TestMarketDataLevel2(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	server, _ := feedServer(t, func(conn *websocket.Conn, n int) {
+		send(t, conn, "l2_data", 0, `{"type":"snapshot","product_id":"BTC-USDC","updates":[`+
+			`{"side":"bid","price_level":"100","new_quantity":"1"},`+
+			`{"side":"bid","price_level":"99","new_quantity":"2"},`+
+			`{"side":"offer","price_level":"101","new_quantity":"1"},`+
+			`{"side":"offer","price_level":"102","new_quantity":"3"}]}`)
+		// The best bid is removed and a better offer arrives
+		send(t, conn, "l2_data", 1, `{"type":"update","product_id":"BTC-USDC","updates":[`+
+			`{"side":"bid","price_level":"100","new_quantity":"0"},`+
+			`{"side":"offer","price_level":"100.5","new_quantity":"1"}]}`)
+		// The ticker's top of book must not override the book
+		send(t, conn, "ticker", 2,
+			`{"type":"update","tickers":[{"product_id":"BTC-USDC","price":"100.2","best_bid":"90","best_ask":"110"}]}`)
+		<-hold
+	})
+
+	m := newTestMarketData(server)
+	runFeed(t, m)
+
+	want := func() bool {
+		quote, ok := m.Quote("BTC-USDC")
+		return ok && quote.LastPrice.Equal(decimal.RequireFromString("100.2"))
+	}
+	if !eventually(t, 2*time.Second, want) {
+		t.Fatal("updates not applied")
+	}
+	quote, _ := m.Quote("BTC-USDC")
+	if !quote.BestBid.Equal(decimal.NewFromInt(99)) || !quote.BestAsk.Equal(decimal.RequireFromString("100.5")) {
+		t.Errorf("top of book = %s / %s, want 99 / 100.5", quote.BestBid, quote.BestAsk)
+	}
+}
+
+func TestMarketDataSequenceGap(t *testing.T) {
+	server, _ := feedServer(t, func(conn *websocket.Conn, n int) {
+		send(t, conn, "heartbeats", 0, `{"current_time":"now","heartbeat_counter":1}`)
+		send(t, conn, "heartbeats", 2, `{"current_time":"now","heartbeat_counter":2}`)
+		conn.ReadMessage() // Wait for the client to hang up
+	})
+
+	m := newTestMarketData(server)
+	received, err := m.stream(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "sequence gap") {
+		t.Fatalf("err = %v, want a sequence gap", err)
+	}
+	if !received {
+		t.Error("received = false, want true after the first message")
+	}
+}
+
+func TestMarketDataSequenceGapReconnects(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	server, connections := feedServer(t, func(conn *websocket.Conn, n int) {
+		send(t, conn, "heartbeats", 5, `{}`)
+		if n == 1 {
+			send(t, conn, "heartbeats", 7, `{}`)
+		}
+		<-hold
+	})
+
+	m := newTestMarketData(server)
+	runFeed(t, m)
+
+	if !eventually(t, minReconnectDelay+2*time.Second, func() bool { return connections.Load() >= 2 }) {
+		t.Fatalf("connections = %d, want a reconnect after the gap", connections.Load())
+	}
+}
+
+func TestMarketDataHeartbeatTimeout(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	server, _ := feedServer(t, func(conn *websocket.Conn, n int) {
+		<-hold // Stay silent
+	})
+
+	m := newTestMarketData(server)
+	m.timeout = 100 * time.Millisecond
+
+	started := time.Now()
+	received, err := m.stream(context.Background())
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("err = %v, want a read timeout", err)
+	}
+	if received {
+		t.Error("received = true, want false for a silent connection")
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("timed out after %s, want about %s", elapsed, m.timeout)
+	}
+}
+
+func TestMarketDataQuoteStaleAfterDisconnect(t *testing.T) {
+	sent := make(chan struct{})
+	server, _ := feedServer(t, func(conn *websocket.Conn, n int) {
+		if n > 1 {
+			conn.ReadMessage() // Reconnects get no data
+			return
+		}
+		send(t, conn, "ticker", 0,
+			`{"type":"update","tickers":[{"product_id":"BTC-USDC","price":"100","best_bid":"99","best_ask":"101"}]}`)
+		<-sent // Hang up once the quote was seen
+	})
+
+	m := newTestMarketData(server)
+	runFeed(t, m)
+
+	if !eventually(t, 2*time.Second, func() bool { _, ok := m.Quote("BTC-USDC"); return ok }) {
+		t.Fatal("no quote received")
+	}
+	close(sent)
+
+	if !eventually(t, 2*time.Second, func() bool { _, ok := m.Quote("BTC-USDC"); return !ok }) {
+		t.Error("quote still served after the connection dropped")
+	}
+}
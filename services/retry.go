@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	core "github.com/coinbase-samples/core-go"
+)
+
+// RetryPolicy controls how failed API calls are retried
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt; 0 disables retrying
+	BaseDelay  time.Duration // backoff before the first retry, doubled for every further retry
+	MaxDelay   time.Duration // upper bound for a single backoff, excluding Retry-After
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// backoff returns the exponential backoff with full jitter for the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// StatusError is returned for HTTP responses with an unexpected status code
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// RetryStats counts retries per operation. It is safe for concurrent use.
+type RetryStats struct {
+	mu      sync.Mutex
+	retries map[string]int
+}
+
+// NewRetryStats creates an empty retry counter
+func NewRetryStats() *RetryStats {
+	return &RetryStats{
+		retries: make(map[string]int),
+	}
+}
+
+// add records a retry of the given operation
+func (s *RetryStats) add(operation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries[operation]++
+}
+
+// Counts returns a copy of the retry counts by operation
+func (s *RetryStats) Counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(s.retries))
+	for operation, count := range s.retries {
+		counts[operation] = count
+	}
+	return counts
+}
+
+// retryStatsKey is the context key for the RetryStats of a run
+type retryStatsKey struct{}
+
+// WithRetryStats returns a context whose API calls record their retries in stats
+func WithRetryStats(ctx context.Context, stats *RetryStats) context.Context {
+	return context.WithValue(ctx, retryStatsKey{}, stats)
+}
+
+// retryAfterKey is the context key used to pass the Retry-After header from the transport back to the retry loop
+type retryAfterKey struct{}
+
+// retryAfterHint holds the Retry-After delay of the last response of an attempt
+type retryAfterHint struct {
+	delay time.Duration
+}
+
+// retryAfterTransport captures the Retry-After header of throttled responses.
+// The SDK does not expose response headers, so the delay is handed back through the request context.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if hint, ok := req.Context().Value(retryAfterKey{}).(*retryAfterHint); ok {
+		hint.delay = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return resp, nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// isRetryable classifies an error as transient (retryable) or fatal
+func isRetryable(err error) bool {
+	var apiErr *core.ApiError
+	if errors.As(err, &apiErr) {
+		// The SDK reports transport failures, including attempt timeouts, with status 0
+		return apiErr.CodeReceived == 0 || retryableStatus(apiErr.CodeReceived)
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryableStatus reports whether an HTTP status indicates a transient failure
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
+
+// withRetry calls fn until it succeeds, fails with a fatal error, the retries are exhausted or ctx is done.
+// Each attempt gets its own context so the transport can report a Retry-After delay.
+func withRetry(ctx context.Context, policy RetryPolicy, operation string, fn func(ctx context.Context) error) error {
+	for retry := 0; ; retry++ {
+		hint := &retryAfterHint{}
+		err := fn(context.WithValue(ctx, retryAfterKey{}, hint))
+		if err == nil {
+			return nil
+		}
+
+		// Stop when the caller gave up, the error is permanent or the budget is spent
+		if ctx.Err() != nil || !isRetryable(err) || retry >= policy.MaxRetries {
+			return err
+		}
+
+		delay := policy.backoff(retry + 1)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		if hint.delay > delay {
+			delay = hint.delay
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("%w (retry after %s would exceed the deadline)", err, delay.Round(time.Millisecond))
+		}
+
+		slog.Warn("Retrying API call", "operation", operation, "retry", retry+1, "delay", delay.Round(time.Millisecond).String(), "error", err)
+		if stats, ok := ctx.Value(retryStatsKey{}).(*RetryStats); ok {
+			stats.add(operation)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
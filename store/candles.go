@@ -0,0 +1,270 @@
+package store
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// CandleStore persists OHLCV price history per product and granularity
+type CandleStore interface {
+	// Candles returns the stored candles starting in [start, end), oldest first
+	Candles(productID, granularity string, start, end time.Time) ([]types.Candle, error)
+	// Put merges candles into the store, replacing stored candles with the same start
+	Put(productID, granularity string, candles []types.Candle) error
+}
+
+// candleHeader is the header of the CSV files written by FileCandleStore
+var candleHeader = []string{"start", "open", "high", "low", "close", "volume"}
+
+// FileCandleStore stores candles as one CSV file per product and granularity in a directory
+type FileCandleStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCandleStore creates a new CSV-backed candle store in dir
+func NewFileCandleStore(dir string) *FileCandleStore {
+	return &FileCandleStore{
+		dir: dir,
+	}
+}
+
+// path returns the file holding the candles of a product at a granularity
+func (s *FileCandleStore) path(productID, granularity string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%s.csv", productID, strings.ToLower(granularity)))
+}
+
+// Candles implements CandleStore
+func (s *FileCandleStore) Candles(productID, granularity string, start, end time.Time) ([]types.Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load(productID, granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	var candles []types.Candle
+	for _, candle := range all {
+		if !candle.Start.Before(start) && candle.Start.Before(end) {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, nil
+}
+
+// Put implements CandleStore
+func (s *FileCandleStore) Put(productID, granularity string, candles []types.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.load(productID, granularity)
+	if err != nil {
+		return err
+	}
+
+	return s.write(productID, granularity, MergeCandles(existing, candles))
+}
+
+// load reads all stored candles of a product at a granularity
+func (s *FileCandleStore) load(productID, granularity string) ([]types.Candle, error) {
+	file, err := os.Open(s.path(productID, granularity))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open candle file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadCandlesCSV(file)
+}
+
+// write replaces the stored candles of a product at a granularity. The file is written to a
+// temporary file first so a failed write never leaves a partial history behind.
+func (s *FileCandleStore) write(productID, granularity string, candles []types.Candle) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create candle directory: %w", err)
+	}
+
+	path := s.path(productID, granularity)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create candle file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := csv.NewWriter(tmp)
+	writer.Write(candleHeader)
+	for _, candle := range candles {
+		writer.Write([]string{
+			candle.Start.UTC().Format(time.RFC3339),
+			candle.Open.String(),
+			candle.High.String(),
+			candle.Low.String(),
+			candle.Close.String(),
+			candle.Volume.String(),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write candle file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write candle file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace candle file: %w", err)
+	}
+	return nil
+}
+
+// MergeCandles combines two candle series ordered by start, preferring the candles of updates
+// where both have the same start
+func MergeCandles(existing, updates []types.Candle) []types.Candle {
+	byStart := make(map[int64]types.Candle, len(existing)+len(updates))
+	for _, candle := range existing {
+		byStart[candle.Start.Unix()] = candle
+	}
+	for _, candle := range updates {
+		byStart[candle.Start.Unix()] = candle
+	}
+
+	merged := make([]types.Candle, 0, len(byStart))
+	for _, candle := range byStart {
+		merged = append(merged, candle)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Start.Before(merged[j].Start)
+	})
+	return merged
+}
+
+// ReadCandlesCSV parses OHLCV candles from CSV with a header row, ordered by start. Columns are
+// matched by name, case-insensitively: start (or time, timestamp, date), open, high, low, close
+// and, optionally, volume. Start times may be RFC 3339, YYYY-MM-DD, or Unix seconds or milliseconds.
+func ReadCandlesCSV(r io.Reader) ([]types.Candle, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candle header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "time", "timestamp", "date":
+			name = "start"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"start", "open", "high", "low", "close"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("candle CSV is missing the %s column", required)
+		}
+	}
+
+	var candles []types.Candle
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read candle on line %d: %w", line, err)
+		}
+
+		candle, err := parseCandleRecord(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("invalid candle on line %d: %w", line, err)
+		}
+		candles = append(candles, candle)
+	}
+
+	return MergeCandles(nil, candles), nil
+}
+
+// parseCandleRecord converts a CSV record using the column positions of the header
+func parseCandleRecord(record []string, columns map[string]int) (types.Candle, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	start, err := parseCandleTime(field("start"))
+	if err != nil {
+		return types.Candle{}, err
+	}
+
+	candle := types.Candle{Start: start}
+	fields := []struct {
+		name     string
+		target   *decimal.Decimal
+		optional bool
+	}{
+		{"open", &candle.Open, false},
+		{"high", &candle.High, false},
+		{"low", &candle.Low, false},
+		{"close", &candle.Close, false},
+		{"volume", &candle.Volume, true},
+	}
+	for _, f := range fields {
+		value := field(f.name)
+		if value == "" && f.optional {
+			continue
+		}
+		if *f.target, err = decimal.NewFromString(value); err != nil {
+			return types.Candle{}, fmt.Errorf("invalid %s %q", f.name, value)
+		}
+	}
+
+	return candle, nil
+}
+
+// parseCandleTime parses an RFC 3339 time, a date, or Unix seconds or milliseconds
+func parseCandleTime(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// Anything past the year 33658 in seconds is taken to be milliseconds
+		if unix > 1e12 {
+			return time.UnixMilli(unix).UTC(), nil
+		}
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid start time %q", value)
+}
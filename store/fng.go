@@ -0,0 +1,108 @@
+package store
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"moonshot/types"
+)
+
+// fngHeader is the header of the Fear & Greed history CSV written by WriteFNGCSV
+var fngHeader = []string{"date", "value", "classification"}
+
+// ReadFNGCSV parses daily Fear & Greed Index values from CSV with a header row, oldest first.
+// Columns are matched by name, case-insensitively: date (or time, timestamp), value and,
+// optionally, classification. Dates are parsed like candle start times.
+func ReadFNGCSV(r io.Reader) ([]types.FearGreedIndex, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FNG header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "time", "timestamp":
+			name = "date"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"date", "value"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("FNG CSV is missing the %s column", required)
+		}
+	}
+
+	var history []types.FearGreedIndex
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FNG value on line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		date, err := parseCandleTime(field("date"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid FNG value on line %d: %w", line, err)
+		}
+		value, err := strconv.Atoi(field("value"))
+		if err != nil || value < 0 || value > 100 {
+			return nil, fmt.Errorf("invalid FNG value on line %d: %q", line, field("value"))
+		}
+		history = append(history, types.FearGreedIndex{
+			Value:          value,
+			Classification: field("classification"),
+			Timestamp:      date,
+		})
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	return history, nil
+}
+
+// WriteFNGCSV writes daily Fear & Greed Index values as CSV that ReadFNGCSV reads back
+func WriteFNGCSV(w io.Writer, history []types.FearGreedIndex) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fngHeader); err != nil {
+		return fmt.Errorf("failed to write FNG header: %w", err)
+	}
+
+	for _, index := range history {
+		record := []string{
+			index.Timestamp.UTC().Format("2006-01-02"),
+			strconv.Itoa(index.Value),
+			index.Classification,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write FNG value: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
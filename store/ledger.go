@@ -0,0 +1,157 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"moonshot/types"
+)
+
+// Ledger entry sources
+const (
+	SourceBot    = "bot"    // Trade placed by this bot
+	SourceManual = "manual" // Trade placed outside the bot
+)
+
+// Ledger records executed trades
+type Ledger interface {
+	Append(entries ...types.LedgerEntry) error
+	Replace(entries []types.LedgerEntry) error
+	Entries() ([]types.LedgerEntry, error)
+}
+
+// FileLedger stores ledger entries as JSON lines on local disk
+type FileLedger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileLedger creates a new file-backed ledger
+func NewFileLedger(path string) *FileLedger {
+	return &FileLedger{
+		path: path,
+	}
+}
+
+// Append adds entries to the end of the ledger
+func (l *FileLedger) Append(entries ...types.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if dir := filepath.Dir(l.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create ledger directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger file: %w", err)
+	}
+	defer file.Close()
+
+	return writeEntries(file, entries)
+}
+
+// Replace overwrites the ledger with entries. The ledger is written to a temporary file first so
+// a failed write never leaves a partial ledger behind.
+func (l *FileLedger) Replace(entries []types.LedgerEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create ledger file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeEntries(tmp, entries); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write ledger file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to replace ledger file: %w", err)
+	}
+	return nil
+}
+
+// writeEntries writes entries as JSON lines
+func writeEntries(w io.Writer, entries []types.LedgerEntry) error {
+	writer := bufio.NewWriter(w)
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ledger entry: %w", err)
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write ledger entry: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write ledger file: %w", err)
+	}
+
+	return nil
+}
+
+// Entries returns all ledger entries ordered by timestamp
+func (l *FileLedger) Entries() ([]types.LedgerEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []types.LedgerEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry types.LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal ledger entry on line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger file: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"moonshot/types"
+)
+
+// StateStore persists bot state between runs
+type StateStore interface {
+	Load() (*types.BotState, error)
+	Save(state *types.BotState) error
+}
+
+// FileStateStore stores bot state as a JSON file on local disk
+type FileStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStateStore creates a new file-backed state store
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{
+		path: path,
+	}
+}
+
+// Load reads the bot state from disk, returning an empty state if none exists yet
+func (s *FileStateStore) Load() (*types.BotState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &types.BotState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	state := &types.BotState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state file: %w", err)
+	}
+
+	return state, nil
+}
+
+// Save writes the bot state to disk atomically
+func (s *FileStateStore) Save(state *types.BotState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated state file
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
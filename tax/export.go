@@ -0,0 +1,133 @@
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"moonshot/types"
+)
+
+// form8949Date is the date layout used on Form 8949
+const form8949Date = "01/02/2006"
+
+// WriteLotsCSV writes acquisitions as a CSV of tax lots
+func WriteLotsCSV(w io.Writer, lots []*types.TaxLot) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"lot_id", "asset", "date_acquired", "quantity", "remaining_quantity",
+		"cost_basis_usd", "fee_usd", "cost_per_unit_usd", "source"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write lots header: %w", err)
+	}
+
+	for _, lot := range lots {
+		record := []string{
+			lot.ID,
+			lot.Asset,
+			lot.Acquired.UTC().Format("2006-01-02T15:04:05Z"),
+			lot.Quantity.String(),
+			lot.RemainingQuantity.String(),
+			lot.CostBasis.StringFixed(2),
+			lot.Fee.StringFixed(2),
+			unitCost(lot).StringFixed(2),
+			lot.Source,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write lot %s: %w", lot.ID, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteForm8949CSV writes disposals in the column layout of IRS Form 8949.
+// Short-term sales are listed under Part I and long-term sales under Part II.
+func WriteForm8949CSV(w io.Writer, disposals []types.Disposal) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"Part", "(a) Description of property", "(b) Date acquired", "(c) Date sold or disposed of",
+		"(d) Proceeds", "(e) Cost or other basis", "(f) Code(s)", "(g) Amount of adjustment", "(h) Gain or (loss)"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write Form 8949 header: %w", err)
+	}
+
+	for _, part := range []bool{false, true} {
+		for _, disposal := range disposals {
+			if disposal.LongTerm != part {
+				continue
+			}
+
+			label := "I"
+			if disposal.LongTerm {
+				label = "II"
+			}
+
+			record := []string{
+				label,
+				fmt.Sprintf("%s %s", disposal.Quantity.String(), disposal.Asset),
+				disposal.Acquired.Format(form8949Date),
+				disposal.Sold.Format(form8949Date),
+				disposal.Proceeds.StringFixed(2),
+				disposal.CostBasis.StringFixed(2),
+				"",
+				"",
+				disposal.Gain.StringFixed(2),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write disposal of lot %s: %w", disposal.LotID, err)
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadSpecificLots reads a "sale_id,lot_id" CSV that assigns lots to sales for specific identification.
+// A sale may appear on several rows; its lots are consumed in row order.
+func ReadSpecificLots(r io.Reader) (map[string][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read specific lot assignments: %w", err)
+	}
+
+	specificLots := make(map[string][]string)
+	for i, record := range records {
+		saleID, lotID := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if i == 0 && strings.EqualFold(saleID, "sale_id") {
+			continue
+		}
+		specificLots[saleID] = append(specificLots[saleID], lotID)
+	}
+
+	return specificLots, nil
+}
+
+// FilterYear keeps lots acquired and disposals made in the given tax year; year 0 keeps everything
+func FilterYear(lots []*types.TaxLot, disposals []types.Disposal, year int) ([]*types.TaxLot, []types.Disposal) {
+	if year == 0 {
+		return lots, disposals
+	}
+
+	var filteredLots []*types.TaxLot
+	for _, lot := range lots {
+		if lot.Acquired.Year() == year {
+			filteredLots = append(filteredLots, lot)
+		}
+	}
+
+	var filteredDisposals []types.Disposal
+	for _, disposal := range disposals {
+		if disposal.Sold.Year() == year {
+			filteredDisposals = append(filteredDisposals, disposal)
+		}
+	}
+
+	return filteredLots, filteredDisposals
+}
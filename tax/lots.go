@@ -0,0 +1,164 @@
+package tax
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"moonshot/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Supported lot matching methods
+const (
+	MethodFIFO       = "fifo"        // First in, first out
+	MethodLIFO       = "lifo"        // Last in, first out
+	MethodHIFO       = "hifo"        // Highest cost first
+	MethodSpecificID = "specific_id" // Lots chosen per sale, remainder FIFO
+)
+
+// LotTracker matches sales against acquisitions to produce tax lots and disposals
+type LotTracker struct {
+	method string
+
+	// specificLots maps a sale's ledger ID to the lot IDs to consume, in order
+	specificLots map[string][]string
+}
+
+// NewLotTracker creates a new lot tracker for the given matching method
+func NewLotTracker(method string, specificLots map[string][]string) (*LotTracker, error) {
+	method = strings.ToLower(method)
+	switch method {
+	case MethodFIFO, MethodLIFO, MethodHIFO, MethodSpecificID:
+	default:
+		return nil, fmt.Errorf("unsupported lot method: %s", method)
+	}
+
+	return &LotTracker{
+		method:       method,
+		specificLots: specificLots,
+	}, nil
+}
+
+// Process turns ledger entries into tax lots (with remaining quantities) and disposals
+func (t *LotTracker) Process(entries []types.LedgerEntry) ([]*types.TaxLot, []types.Disposal, error) {
+	sorted := append([]types.LedgerEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var lots []*types.TaxLot
+	var disposals []types.Disposal
+	openLots := make(map[string][]*types.TaxLot)
+
+	for _, entry := range sorted {
+		if !strings.EqualFold(entry.Side, "SELL") {
+			lot := &types.TaxLot{
+				ID:                entry.ID,
+				Asset:             entry.Asset,
+				Acquired:          entry.Timestamp,
+				Quantity:          entry.Quantity,
+				RemainingQuantity: entry.Quantity,
+				CostBasis:         entry.QuoteAmount.Add(entry.Fee),
+				Fee:               entry.Fee,
+				Source:            entry.Source,
+			}
+			lots = append(lots, lot)
+			openLots[entry.Asset] = append(openLots[entry.Asset], lot)
+			continue
+		}
+
+		sold, err := t.dispose(entry, openLots[entry.Asset])
+		if err != nil {
+			return nil, nil, err
+		}
+		disposals = append(disposals, sold...)
+	}
+
+	return lots, disposals, nil
+}
+
+// dispose consumes open lots for a single sale
+func (t *LotTracker) dispose(sale types.LedgerEntry, open []*types.TaxLot) ([]types.Disposal, error) {
+	var disposals []types.Disposal
+	remaining := sale.Quantity
+	netProceeds := sale.QuoteAmount.Sub(sale.Fee)
+
+	for _, lot := range t.orderLots(sale.ID, open) {
+		if remaining.LessThanOrEqual(decimal.Zero) {
+			break
+		}
+		if lot.RemainingQuantity.LessThanOrEqual(decimal.Zero) {
+			continue
+		}
+
+		quantity := decimal.Min(remaining, lot.RemainingQuantity)
+		costBasis := lot.CostBasis.Mul(quantity).Div(lot.Quantity)
+		proceeds := netProceeds.Mul(quantity).Div(sale.Quantity)
+
+		disposals = append(disposals, types.Disposal{
+			LotID:     lot.ID,
+			SaleID:    sale.ID,
+			Asset:     sale.Asset,
+			Quantity:  quantity,
+			Acquired:  lot.Acquired,
+			Sold:      sale.Timestamp,
+			Proceeds:  proceeds.Round(2),
+			CostBasis: costBasis.Round(2),
+			Gain:      proceeds.Sub(costBasis).Round(2),
+			LongTerm:  sale.Timestamp.After(lot.Acquired.AddDate(1, 0, 0)),
+		})
+
+		lot.RemainingQuantity = lot.RemainingQuantity.Sub(quantity)
+		remaining = remaining.Sub(quantity)
+	}
+
+	if remaining.GreaterThan(decimal.Zero) {
+		return nil, fmt.Errorf("sale %s of %s %s exceeds tracked lots by %s; import missing acquisitions first",
+			sale.ID, sale.Quantity.String(), sale.Asset, remaining.String())
+	}
+
+	return disposals, nil
+}
+
+// orderLots returns open lots in the order they should be consumed
+func (t *LotTracker) orderLots(saleID string, open []*types.TaxLot) []*types.TaxLot {
+	ordered := append([]*types.TaxLot{}, open...)
+
+	switch t.method {
+	case MethodLIFO:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Acquired.After(ordered[j].Acquired)
+		})
+
+	case MethodHIFO:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return unitCost(ordered[i]).GreaterThan(unitCost(ordered[j]))
+		})
+
+	case MethodSpecificID:
+		// Chosen lots first, in the order given, then the rest FIFO
+		rank := make(map[string]int)
+		for i, id := range t.specificLots[saleID] {
+			rank[id] = i + 1
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ri, rj := rank[ordered[i].ID], rank[ordered[j].ID]
+			if ri == 0 || rj == 0 {
+				return ri != 0 && rj == 0
+			}
+			return ri < rj
+		})
+	}
+
+	return ordered
+}
+
+// unitCost returns the lot's cost per unit
+func unitCost(lot *types.TaxLot) decimal.Decimal {
+	if lot.Quantity.IsZero() {
+		return decimal.Zero
+	}
+	return lot.CostBasis.Div(lot.Quantity)
+}
@@ -9,18 +9,24 @@ import (
 
 // Asset represents a cryptocurrency asset
 type Asset struct {
-	Symbol     string          `json:"symbol"`
-	Name       string          `json:"name"`
-	Allocation decimal.Decimal `json:"allocation"`
-	Balance    decimal.Decimal `json:"balance"`
-	Price      decimal.Decimal `json:"price"`
-	Value      decimal.Decimal `json:"value"`
+	Symbol      string          `json:"symbol"`
+	Name        string          `json:"name"`
+	Allocation  decimal.Decimal `json:"allocation"`
+	Balance     decimal.Decimal `json:"balance"` // available plus hold
+	Available   decimal.Decimal `json:"available"`
+	Hold        decimal.Decimal `json:"hold"`
+	Price       decimal.Decimal `json:"price"`
+	PriceSource string          `json:"price_source,omitempty"` // bid, ask or mid
+	Value       decimal.Decimal `json:"value"`
+	Status      string          `json:"status"` // ok or error
+	Error       string          `json:"error,omitempty"`
 }
 
 // Portfolio represents the current portfolio state
 type Portfolio struct {
 	TotalValue  decimal.Decimal   `json:"total_value"`
-	USDCBalance decimal.Decimal   `json:"usdc_balance"`
+	USDCBalance decimal.Decimal   `json:"usdc_balance"` // available for investing
+	USDCHold    decimal.Decimal   `json:"usdc_hold"`
 	Assets      map[string]*Asset `json:"assets"`
 	LastUpdated time.Time         `json:"last_updated"`
 }
@@ -40,7 +46,23 @@ type InvestmentDecision struct {
 	Amount    decimal.Decimal `json:"amount"`
 	Price     decimal.Decimal `json:"price"`
 	Reason    string          `json:"reason"`
+	Tag       string          `json:"tag,omitempty"` // ledger tag of the resulting fills; dca if empty
 	Timestamp time.Time       `json:"timestamp"`
+
+	Multiplier decimal.Decimal            `json:"multiplier"`           // combined multiplier applied to the base investment
+	Indicators map[string]decimal.Decimal `json:"indicators,omitempty"` // indicator values the multiplier was derived from
+	Signals    map[string]decimal.Decimal `json:"signals,omitempty"`    // multiplier of each weighted signal
+	Volatility *VolatilityAdjustment      `json:"volatility,omitempty"` // volatility scaling applied to the multiplier
+}
+
+// VolatilityAdjustment records how realized volatility scaled a decision's multiplier
+type VolatilityAdjustment struct {
+	Mode            string          `json:"mode"` // inverse or direct
+	RealizedPercent decimal.Decimal `json:"realized_percent"`
+	TargetPercent   decimal.Decimal `json:"target_percent"`
+	Factor          decimal.Decimal `json:"factor"`            // applied to the signal multiplier
+	BaseMultiplier  decimal.Decimal `json:"base_multiplier"`   // signal multiplier before scaling
+	Clamped         bool            `json:"clamped,omitempty"` // the result was held within the multiplier bounds
 }
 
 // MarketData represents current market information
@@ -52,37 +74,353 @@ type MarketData struct {
 	Timestamp time.Time       `json:"timestamp"`
 }
 
+// Candle is an OHLCV price bar starting at Start
+type Candle struct {
+	Start  time.Time       `json:"start"`
+	Open   decimal.Decimal `json:"open"`
+	High   decimal.Decimal `json:"high"`
+	Low    decimal.Decimal `json:"low"`
+	Close  decimal.Decimal `json:"close"`
+	Volume decimal.Decimal `json:"volume"`
+}
+
 // BotConfig represents the bot configuration
 type BotConfig struct {
-	BTCAllocation        decimal.Decimal `json:"btc_allocation"`
-	ETHAllocation        decimal.Decimal `json:"eth_allocation"`
-	WeeklyBaseInvestment decimal.Decimal `json:"weekly_base_investment"`
-	FNGBuyThreshold      int             `json:"fng_buy_threshold"`
-	MinMultiplier        decimal.Decimal `json:"min_multiplier"`
-	MaxMultiplier        decimal.Decimal `json:"max_multiplier"`
-	InvestmentFrequency  string          `json:"investment_frequency"`
-	ExecutionTime        string          `json:"execution_time"`
+	BTCAllocation         decimal.Decimal   `json:"btc_allocation"`
+	ETHAllocation         decimal.Decimal   `json:"eth_allocation"`
+	WeeklyBaseInvestment  decimal.Decimal   `json:"weekly_base_investment"`
+	FNGBuyThreshold       int               `json:"fng_buy_threshold"`
+	MinMultiplier         decimal.Decimal   `json:"min_multiplier"`
+	MaxMultiplier         decimal.Decimal   `json:"max_multiplier"`
+	InvestmentFrequency   string            `json:"investment_frequency"`
+	ExecutionTime         string            `json:"execution_time"`
+	ExecutionWeekday      string            `json:"execution_weekday"`
+	ExecutionDayOfMonth   int               `json:"execution_day_of_month"`
+	Timezone              string            `json:"timezone"`
+	CatchUpPolicy         string            `json:"catch_up_policy"`
+	CatchUpSpreadRuns     int               `json:"catch_up_spread_runs"`
+	MaxDailyInvestment    decimal.Decimal   `json:"max_daily_investment"`
+	StrictValuation       bool              `json:"strict_valuation"`    // fail the run if any asset can't be valued
+	MaxPriceDeviation     decimal.Decimal   `json:"max_price_deviation"` // percent the live price may move before an order is blocked
+	ExecutionMode         string            `json:"execution_mode"`      // market or limit
+	LimitOrderTimeout     time.Duration     `json:"limit_order_timeout"`
+	LimitMaxReprices      int               `json:"limit_max_reprices"`
+	LimitPriceTicks       int               `json:"limit_price_ticks"`     // price increments above the best bid
+	LimitMarketFallback   bool              `json:"limit_market_fallback"` // buy the unfilled remainder at market
+	LadderEnabled         bool              `json:"ladder_enabled"`
+	LadderLevels          []decimal.Decimal `json:"ladder_levels"`       // percent below market of each rung
+	LadderBufferShare     decimal.Decimal   `json:"ladder_buffer_share"` // percent of the dip buying buffer placed on the ladder
+	TWAPSlices            int               `json:"twap_slices"`         // child orders per TWAP parent; 1 disables TWAP
+	TWAPWindow            time.Duration     `json:"twap_window"`
+	TWAPMinAmount         decimal.Decimal   `json:"twap_min_amount"` // smallest decision split into child orders
+	DipTriggerEnabled     bool              `json:"dip_trigger_enabled"`
+	DipCheckInterval      time.Duration     `json:"dip_check_interval"`
+	DipDrawdownPercent    decimal.Decimal   `json:"dip_drawdown_percent"` // drop from the lookback high that fires; 0 disables
+	DipLookback           time.Duration     `json:"dip_lookback"`
+	DipBuyPercent         decimal.Decimal   `json:"dip_buy_percent"` // percent of the reserve spent per dip buy
+	DipCooldown           time.Duration     `json:"dip_cooldown"`
+	DipMaxBuysPerPeriod   int               `json:"dip_max_buys_per_period"`
+	DipMaxAmountPerPeriod decimal.Decimal   `json:"dip_max_amount_per_period"` // 0 means no cap
+	FNGWeight             decimal.Decimal   `json:"fng_weight"`                // weights of the signals combined into the multiplier
+	RSIWeight             decimal.Decimal   `json:"rsi_weight"`
+	MayerWeight           decimal.Decimal   `json:"mayer_weight"`
+	ATHWeight             decimal.Decimal   `json:"ath_weight"`
+	IndicatorHistory      time.Duration     `json:"indicator_history"`   // daily candles fetched for indicators and the all-time high
+	Strategy              string            `json:"strategy"`            // dca or value_averaging
+	VATargetIncrement     decimal.Decimal   `json:"va_target_increment"` // target value added per period
+	VAGrowthPercent       decimal.Decimal   `json:"va_growth_percent"`   // growth of the increment per period
+	TakeProfitEnabled     bool              `json:"take_profit_enabled"`
+	TakeProfitCondition   string            `json:"take_profit_condition"`  // any or all of the enabled conditions
+	FNGSellThreshold      int               `json:"fng_sell_threshold"`     // sell at or above this F&G value; 0 disables
+	TakeProfitPercentage  decimal.Decimal   `json:"take_profit_percentage"` // sell at or above this gain over average cost; 0 disables
+	SellPercentage        decimal.Decimal   `json:"sell_percentage"`        // percent of the available holdings sold
+	MaxSellPerPeriod      decimal.Decimal   `json:"max_sell_per_period"`    // USDC sold per schedule period; 0 means no cap
+	VolatilitySizing      string            `json:"volatility_sizing"`      // off, inverse or direct
+	VolatilityLookback    int               `json:"volatility_lookback"`    // days of returns the volatility is measured over
+	VolatilityTarget      decimal.Decimal   `json:"volatility_target"`      // annualized volatility percent at which the factor is 1
 }
 
 // CoinbaseConfig represents Coinbase Advanced API configuration
 type CoinbaseConfig struct {
-	APIKey    string `json:"api_key"`
-	APISecret string `json:"api_secret"`
-	Sandbox   bool   `json:"sandbox"`
+	APIKey         string        `json:"api_key"`
+	APISecret      string        `json:"api_secret"`
+	Sandbox        bool          `json:"sandbox"`
+	RequestTimeout time.Duration `json:"request_timeout"`
+	PriceSource    string        `json:"price_source"`    // bid, ask or mid for portfolio valuation
+	MaxConcurrency int           `json:"max_concurrency"` // concurrent API requests when gathering market data
+}
+
+// LoggingConfig represents the logging configuration
+type LoggingConfig struct {
+	Level    string `json:"level"`  // debug, info, warn, error
+	Format   string `json:"format"` // text, json
+	Output   string `json:"output"` // stdout, file
+	FilePath string `json:"file_path"`
 }
 
+// NotificationConfig represents the notification channel configuration
+type NotificationConfig struct {
+	Enabled          bool     `json:"enabled"`
+	SlackWebhookURL  string   `json:"slack_webhook_url"`
+	TelegramBotToken string   `json:"telegram_bot_token"`
+	TelegramChatID   string   `json:"telegram_chat_id"`
+	SMTPServer       string   `json:"smtp_server"`
+	SMTPPort         int      `json:"smtp_port"`
+	SMTPUsername     string   `json:"smtp_username"`
+	SMTPPassword     string   `json:"smtp_password"`
+	EmailFrom        string   `json:"email_from"`
+	EmailRecipients  []string `json:"email_recipients"`
+	WebhookURL       string   `json:"webhook_url"`
+}
+
+// Quote is the live top of book and last trade for a product
+type Quote struct {
+	ProductID string          `json:"product_id"`
+	BestBid   decimal.Decimal `json:"best_bid"`
+	BestAsk   decimal.Decimal `json:"best_ask"`
+	LastPrice decimal.Decimal `json:"last_price"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// MarketSnapshot is a consistent point-in-time view of the portfolio, prices and sentiment
+type MarketSnapshot struct {
+	Timestamp time.Time                  `json:"timestamp"`
+	Portfolio *Portfolio                 `json:"portfolio"`
+	FNGIndex  *FearGreedIndex            `json:"fng_index"`
+	Prices    map[string]decimal.Decimal `json:"prices"` // assets that could be valued
+
+	Indicators map[string]map[string]decimal.Decimal `json:"indicators,omitempty"` // indicator values by asset
+}
+
+// RunKind identifies the entry point a bot execution was started from
+type RunKind string
+
+// Kinds of bot executions
+const (
+	RunKindDCA      RunKind = "dca"       // Scheduled DCA run
+	RunKindDipCheck RunKind = "dip_check" // Dip check between scheduled runs
+	RunKindTWAP     RunKind = "twap"      // Continuation of pending TWAP child orders
+)
+
 // ExecutionResult represents the result of a bot execution
 type ExecutionResult struct {
+	RunID         string               `json:"run_id"`
 	Success       bool                 `json:"success"`
 	Decisions     []InvestmentDecision `json:"decisions"`
 	Portfolio     *Portfolio           `json:"portfolio"`
 	FNGIndex      *FearGreedIndex      `json:"fng_index"`
 	TotalInvested decimal.Decimal      `json:"total_invested"`
 	TotalSold     decimal.Decimal      `json:"total_sold"`
+	RealizedPnL   decimal.Decimal      `json:"realized_pnl"` // of this run's take-profit sells
+	Orders        []OrderResult        `json:"orders,omitempty"`
+	BufferPercent decimal.Decimal      `json:"buffer_percent"`
+	Retries       map[string]int       `json:"retries,omitempty"` // API call retries by operation
+	FeeSavings    decimal.Decimal      `json:"fee_savings"`       // taker fees avoided by maker fills
+	Ladder        *LadderSummary       `json:"ladder,omitempty"`
+	TWAP          []TWAPSummary        `json:"twap,omitempty"`
+	CatchUp       *CatchUpSummary      `json:"catch_up,omitempty"`
+	ValueAverage  *ValueAverageSummary `json:"value_average,omitempty"`
+	Performance   *PerformanceReport   `json:"performance,omitempty"`
 	Timestamp     time.Time            `json:"timestamp"`
 	Error         string               `json:"error,omitempty"`
 }
 
+// OrderResult records the outcome of a single order placed during a run
+type OrderResult struct {
+	Asset        string           `json:"asset"`
+	Side         string           `json:"side"`
+	OrderID      string           `json:"order_id,omitempty"`
+	Amount       decimal.Decimal  `json:"amount"`
+	Execution    string           `json:"execution,omitempty"` // market, limit or limit+market
+	ParentID     string           `json:"parent_id,omitempty"` // TWAP parent of a child order
+	FilledSize   decimal.Decimal  `json:"filled_size"`
+	AveragePrice decimal.Decimal  `json:"average_price"`
+	Fee          decimal.Decimal  `json:"fee"`
+	FeeSavings   decimal.Decimal  `json:"fee_savings"`
+	RealizedPnL  *decimal.Decimal `json:"realized_pnl,omitempty"` // sells only: proceeds less fees and average cost
+	Success      bool             `json:"success"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// CatchUpSummary describes how missed DCA periods were handled during a run
+type CatchUpSummary struct {
+	Policy          string          `json:"policy"`
+	MissedPeriods   []time.Time     `json:"missed_periods,omitempty"`
+	CaughtUpPeriods []time.Time     `json:"caught_up_periods,omitempty"`
+	PendingPeriods  []time.Time     `json:"pending_periods,omitempty"`
+	Amount          decimal.Decimal `json:"amount"`
+}
+
+// ValueAverageSummary describes where the portfolio stands against the value averaging target path
+type ValueAverageSummary struct {
+	StartedAt time.Time       `json:"started_at"`
+	Period    int             `json:"period"`    // scheduled periods since the start, this one included
+	Target    decimal.Decimal `json:"target"`    // value the invested assets should have this period
+	Value     decimal.Decimal `json:"value"`     // current value of the invested assets, including pending TWAP children
+	Shortfall decimal.Decimal `json:"shortfall"` // target less value; negative when ahead of target
+	Amount    decimal.Decimal `json:"amount"`    // shortfall to buy this run after caps
+}
+
+// LedgerEntry represents a single executed trade recorded in the trade ledger
+type LedgerEntry struct {
+	ID            string          `json:"id"`
+	OrderID       string          `json:"order_id"`
+	ClientOrderID string          `json:"client_order_id,omitempty"`
+	Asset         string          `json:"asset"`
+	ProductID     string          `json:"product_id"`
+	Side          string          `json:"side"` // BUY or SELL
+	Quantity      decimal.Decimal `json:"quantity"`
+	Price         decimal.Decimal `json:"price"`
+	QuoteAmount   decimal.Decimal `json:"quote_amount"` // Quantity * Price, excluding fees
+	Fee           decimal.Decimal `json:"fee"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Source        string          `json:"source"` // bot or manual
+	Tag           string          `json:"tag,omitempty"`
+	Estimated     bool            `json:"estimated,omitempty"` // Fill details were not available from the exchange
+}
+
+// AssetPerformance represents cost basis and returns for a single asset
+type AssetPerformance struct {
+	Asset                string          `json:"asset"`
+	Quantity             decimal.Decimal `json:"quantity"`
+	TotalInvested        decimal.Decimal `json:"total_invested"`
+	TotalProceeds        decimal.Decimal `json:"total_proceeds"`
+	CostBasis            decimal.Decimal `json:"cost_basis"`
+	AverageCost          decimal.Decimal `json:"average_cost"`
+	CurrentPrice         decimal.Decimal `json:"current_price"`
+	CurrentValue         decimal.Decimal `json:"current_value"`
+	UnrealizedPnL        decimal.Decimal `json:"unrealized_pnl"`
+	UnrealizedPnLPercent decimal.Decimal `json:"unrealized_pnl_percent"`
+	RealizedPnL          decimal.Decimal `json:"realized_pnl"`
+	TimeWeightedReturn   decimal.Decimal `json:"time_weighted_return"`
+	MoneyWeightedReturn  decimal.Decimal `json:"money_weighted_return"` // Annualized XIRR
+	FirstTrade           time.Time       `json:"first_trade"`
+	Trades               int             `json:"trades"`
+}
+
+// PerformanceReport represents portfolio performance computed from the trade ledger
+type PerformanceReport struct {
+	Assets  map[string]*AssetPerformance `json:"assets"`
+	Overall *AssetPerformance            `json:"overall"`
+	AsOf    time.Time                    `json:"as_of"`
+}
+
+// TaxLot represents an acquisition of an asset tracked for tax purposes
+type TaxLot struct {
+	ID                string          `json:"id"`
+	Asset             string          `json:"asset"`
+	Acquired          time.Time       `json:"acquired"`
+	Quantity          decimal.Decimal `json:"quantity"`
+	RemainingQuantity decimal.Decimal `json:"remaining_quantity"`
+	CostBasis         decimal.Decimal `json:"cost_basis"` // USD cost including fees
+	Fee               decimal.Decimal `json:"fee"`
+	Source            string          `json:"source"`
+}
+
+// Disposal represents the sale of (part of) a tax lot
+type Disposal struct {
+	LotID     string          `json:"lot_id"`
+	SaleID    string          `json:"sale_id"`
+	Asset     string          `json:"asset"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Acquired  time.Time       `json:"acquired"`
+	Sold      time.Time       `json:"sold"`
+	Proceeds  decimal.Decimal `json:"proceeds"` // USD proceeds net of fees
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	Gain      decimal.Decimal `json:"gain"`
+	LongTerm  bool            `json:"long_term"`
+}
+
+// BotState represents the persisted state of the bot between runs
+type BotState struct {
+	LastRunAt       time.Time `json:"last_run_at"`
+	LastScheduledAt time.Time `json:"last_scheduled_at"`
+	LastRunSuccess  bool      `json:"last_run_success"`
+	LastError       string    `json:"last_error,omitempty"`
+	TotalRuns       int       `json:"total_runs"`
+
+	// Missed periods waiting to be caught up and how many runs remain to spread them over
+	PendingCatchUp  []time.Time `json:"pending_catch_up,omitempty"`
+	CatchUpRunsLeft int         `json:"catch_up_runs_left,omitempty"`
+	LastCaughtUp    []time.Time `json:"last_caught_up,omitempty"`
+
+	// Ladder orders resting on the book, settled and replaced by the next run
+	LadderOrders []LadderOrder `json:"ladder_orders,omitempty"`
+
+	// TWAP parent orders with child orders still to execute
+	TWAPOrders []TWAPOrder `json:"twap_orders,omitempty"`
+
+	// Reserve left for dip buys by the last run, and dip buys made in the current schedule period
+	DipReserve       decimal.Decimal `json:"dip_reserve"`
+	LastDipBuyAt     time.Time       `json:"last_dip_buy_at,omitempty"`
+	DipPeriod        time.Time       `json:"dip_period,omitempty"`
+	DipBuysInPeriod  int             `json:"dip_buys_in_period,omitempty"`
+	DipSpentInPeriod decimal.Decimal `json:"dip_spent_in_period"`
+
+	// Start of the value averaging target path and the invested value it starts from
+	VAStartedAt  time.Time       `json:"va_started_at,omitempty"`
+	VAStartValue decimal.Decimal `json:"va_start_value"`
+
+	// Take-profit sales made in the current schedule period
+	SellPeriod   time.Time       `json:"sell_period,omitempty"`
+	SoldInPeriod decimal.Decimal `json:"sold_in_period"`
+}
+
+// TWAPOrder is a buy decision split into child orders spread over a time window
+type TWAPOrder struct {
+	ID           string             `json:"id"`
+	Decision     InvestmentDecision `json:"decision"`
+	ArrivalPrice decimal.Decimal    `json:"arrival_price"` // price when the parent was created
+	CreatedAt    time.Time          `json:"created_at"`
+	Children     []TWAPChild        `json:"children"`
+}
+
+// TWAPChild is a single slice of a TWAP parent order
+type TWAPChild struct {
+	Index        int             `json:"index"`
+	DueAt        time.Time       `json:"due_at"`
+	Amount       decimal.Decimal `json:"amount"`
+	Status       string          `json:"status"` // pending, done or failed
+	OrderID      string          `json:"order_id,omitempty"`
+	Invested     decimal.Decimal `json:"invested"`
+	FilledSize   decimal.Decimal `json:"filled_size"`
+	AveragePrice decimal.Decimal `json:"average_price"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// TWAPSummary reports the progress and execution quality of a TWAP parent order
+type TWAPSummary struct {
+	ParentID        string          `json:"parent_id"`
+	Asset           string          `json:"asset"`
+	Amount          decimal.Decimal `json:"amount"`
+	Invested        decimal.Decimal `json:"invested"` // by all children so far
+	ArrivalPrice    decimal.Decimal `json:"arrival_price"`
+	VWAP            decimal.Decimal `json:"vwap"`
+	SlippagePercent decimal.Decimal `json:"slippage_percent"` // VWAP above the arrival price
+	ChildrenDone    int             `json:"children_done"`
+	Children        int             `json:"children"`
+	Complete        bool            `json:"complete"`
+}
+
+// LadderOrder is a GTC limit buy placed below market from the dip buying buffer
+type LadderOrder struct {
+	OrderID  string          `json:"order_id"`
+	Asset    string          `json:"asset"`
+	Level    decimal.Decimal `json:"level"` // percent below market
+	Price    decimal.Decimal `json:"price"`
+	Size     decimal.Decimal `json:"size"`
+	Amount   decimal.Decimal `json:"amount"`
+	PlacedAt time.Time       `json:"placed_at"`
+}
+
+// LadderSummary describes how the ladder was settled and replaced during a run
+type LadderSummary struct {
+	Filled    decimal.Decimal `json:"filled"`    // USDC spent by fills of the previous rungs, including fees
+	Cancelled int             `json:"cancelled"` // unfilled previous rungs cancelled
+	Budget    decimal.Decimal `json:"budget"`    // USDC of the buffer committed to the new rungs
+	Orders    []LadderOrder   `json:"orders,omitempty"`
+}
+
 // Helper functions for decimal operations
 func DecimalFromFloat(f float64) decimal.Decimal {
 	return decimal.NewFromFloat(f)